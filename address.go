@@ -0,0 +1,104 @@
+package tplinkddm
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SNMP transports
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+)
+
+// Address is a switch's SNMP address, parsed from a target such as
+// "192.0.2.1", "switch.example.com:1161", "tcp://192.0.2.1" or
+// "udp://[2001:db8::1]:161".
+type Address struct {
+	Transport string // TransportUDP or TransportTCP, empty for the default (UDP)
+	Host      string // hostname or IP, without brackets
+	Port      uint16 // 0 for the default (161)
+}
+
+// ParseAddress parses a target address. IPv6 literals need brackets when
+// they have a port or scheme, but a bare IPv6 literal is also accepted.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+
+	rest := s
+	if scheme, after, ok := strings.Cut(s, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case TransportUDP, TransportTCP:
+			a.Transport = strings.ToLower(scheme)
+		default:
+			return Address{}, fmt.Errorf("address %q: unsupported scheme %q (want udp or tcp)", s, scheme)
+		}
+
+		rest = after
+	}
+
+	if rest == "" {
+		return Address{}, fmt.Errorf("address %q: missing host", s)
+	}
+
+	// a bare IPv6 literal has colons but no port
+	if ip := net.ParseIP(rest); ip != nil {
+		a.Host = ip.String()
+
+		return a, nil
+	}
+
+	if strings.HasPrefix(rest, "[") && strings.HasSuffix(rest, "]") {
+		rest = strings.TrimSuffix(strings.TrimPrefix(rest, "["), "]")
+		if net.ParseIP(rest) == nil {
+			return Address{}, fmt.Errorf("address %q: invalid IPv6 literal", s)
+		}
+
+		a.Host = rest
+
+		return a, nil
+	}
+
+	if !strings.Contains(rest, ":") {
+		a.Host = rest
+
+		return a, nil
+	}
+
+	host, port, err := net.SplitHostPort(rest)
+	if err != nil {
+		return Address{}, fmt.Errorf("address %q: %w", s, err)
+	}
+
+	if host == "" {
+		return Address{}, fmt.Errorf("address %q: missing host", s)
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return Address{}, fmt.Errorf("address %q: invalid port %q", s, port)
+	}
+
+	a.Host = host
+	a.Port = uint16(p)
+
+	return a, nil
+}
+
+// ClientOptions returns the SNMP client options for the address's transport
+// and port, if given
+func (a Address) ClientOptions() []ClientOption {
+	var opts []ClientOption
+
+	if a.Transport != "" {
+		opts = append(opts, WithTransport(a.Transport))
+	}
+
+	if a.Port != 0 {
+		opts = append(opts, WithPort(a.Port))
+	}
+
+	return opts
+}
@@ -0,0 +1,85 @@
+package tplinkddm
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  Address
+	}{
+		{"192.0.2.1", Address{Host: "192.0.2.1"}},
+		{"192.0.2.1:1161", Address{Host: "192.0.2.1", Port: 1161}},
+		{"switch.example.com", Address{Host: "switch.example.com"}},
+		{"switch.example.com:1161", Address{Host: "switch.example.com", Port: 1161}},
+		{"udp://192.0.2.1", Address{Transport: TransportUDP, Host: "192.0.2.1"}},
+		{"tcp://192.0.2.1:1161", Address{Transport: TransportTCP, Host: "192.0.2.1", Port: 1161}},
+		{"TCP://switch", Address{Transport: TransportTCP, Host: "switch"}},
+		{"2001:db8::1", Address{Host: "2001:db8::1"}},
+		{"[2001:db8::1]", Address{Host: "2001:db8::1"}},
+		{"[2001:db8::1]:1161", Address{Host: "2001:db8::1", Port: 1161}},
+		{"udp://[2001:db8::1]:161", Address{Transport: TransportUDP, Host: "2001:db8::1", Port: 161}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseAddress(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseAddress_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"",
+		"http://192.0.2.1",
+		"udp://",
+		"192.0.2.1:snmp",
+		"192.0.2.1:0",
+		"192.0.2.1:70000",
+		":161",
+		"[not-an-ip]",
+		"[2001:db8::1]:",
+	} {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseAddress(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGetDDMMetrics_IPv6(t *testing.T) {
+	t.Parallel()
+
+	agent := listenTestAgent(t, net.IPv6loopback, &gosnmp.GoSNMP{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		Logger:    gosnmp.NewLogger(nil),
+	}, testDDMVars())
+
+	tc := TargetConfig{Address: fmt.Sprintf("udp://[::1]:%d", agent.port)}
+
+	client, err := tc.NewClient(Auth{Version: 2, Community: "public"})
+	require.NoError(t, err)
+
+	result, err := client.GetDDMMetrics(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "agent-switch", result.SysName)
+	assert.Len(t, result.Metrics, 2)
+}
@@ -0,0 +1,149 @@
+package tplinkddm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SNMPv3 security levels
+const (
+	SecurityLevelNoAuthNoPriv = "noAuthNoPriv"
+	SecurityLevelAuthNoPriv   = "authNoPriv"
+	SecurityLevelAuthPriv     = "authPriv"
+)
+
+// ErrInvalidAuth is returned when a client's credentials are incomplete or
+// name an unsupported protocol
+var ErrInvalidAuth = errors.New("invalid auth")
+
+// Auth holds the credentials used to query a switch. Version 2 (the default)
+// uses Community; version 3 uses the USM fields.
+type Auth struct {
+	Community     string
+	Username      string
+	SecurityLevel string // noAuthNoPriv, authNoPriv or authPriv
+	AuthProtocol  string // MD5, SHA, SHA224, SHA256, SHA384, SHA512
+	AuthPassword  string
+	PrivProtocol  string // DES, AES, AES192, AES256, AES192C, AES256C
+	PrivPassword  string
+	ContextName   string
+	Version       int // 2 or 3
+}
+
+//nolint:gochecknoglobals // lookup tables
+var (
+	authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+		"MD5":    gosnmp.MD5,
+		"SHA":    gosnmp.SHA,
+		"SHA224": gosnmp.SHA224,
+		"SHA256": gosnmp.SHA256,
+		"SHA384": gosnmp.SHA384,
+		"SHA512": gosnmp.SHA512,
+	}
+
+	privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+		"DES":     gosnmp.DES,
+		"AES":     gosnmp.AES,
+		"AES192":  gosnmp.AES192,
+		"AES256":  gosnmp.AES256,
+		"AES192C": gosnmp.AES192C,
+		"AES256C": gosnmp.AES256C,
+	}
+)
+
+// Validate checks that the credentials are complete and that the named
+// protocols are supported.
+//
+//nolint:gocyclo // one check per security level
+func (a Auth) Validate() error {
+	switch a.Version {
+	case 0, 2:
+		if a.Community == "" {
+			return errors.New("community is required for SNMP v2c")
+		}
+
+		return nil
+	case 3:
+	default:
+		return fmt.Errorf("unsupported SNMP version %d", a.Version)
+	}
+
+	if a.Username == "" {
+		return errors.New("username is required for SNMP v3")
+	}
+
+	switch a.securityLevel() {
+	case SecurityLevelAuthPriv:
+		if _, ok := privProtocols[strings.ToUpper(a.PrivProtocol)]; !ok {
+			return fmt.Errorf("unsupported priv protocol %q", a.PrivProtocol)
+		}
+
+		if a.PrivPassword == "" {
+			return errors.New("priv password is required for authPriv")
+		}
+
+		fallthrough
+	case SecurityLevelAuthNoPriv:
+		if _, ok := authProtocols[strings.ToUpper(a.AuthProtocol)]; !ok {
+			return fmt.Errorf("unsupported auth protocol %q", a.AuthProtocol)
+		}
+
+		if a.AuthPassword == "" {
+			return errors.New("auth password is required for " + a.securityLevel())
+		}
+	case SecurityLevelNoAuthNoPriv:
+	default:
+		return fmt.Errorf("unsupported security level %q", a.SecurityLevel)
+	}
+
+	return nil
+}
+
+func (a Auth) securityLevel() string {
+	if a.SecurityLevel == "" {
+		return SecurityLevelNoAuthNoPriv
+	}
+
+	return a.SecurityLevel
+}
+
+// configure sets the version and credentials on a gosnmp client
+func (a Auth) configure(client *gosnmp.GoSNMP) error {
+	if err := a.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAuth, err)
+	}
+
+	if a.Version != 3 {
+		client.Version = gosnmp.Version2c
+		client.Community = a.Community
+
+		return nil
+	}
+
+	usm := &gosnmp.UsmSecurityParameters{UserName: a.Username}
+
+	switch a.securityLevel() {
+	case SecurityLevelAuthPriv:
+		client.MsgFlags = gosnmp.AuthPriv
+		usm.PrivacyProtocol = privProtocols[strings.ToUpper(a.PrivProtocol)]
+		usm.PrivacyPassphrase = a.PrivPassword
+		usm.AuthenticationProtocol = authProtocols[strings.ToUpper(a.AuthProtocol)]
+		usm.AuthenticationPassphrase = a.AuthPassword
+	case SecurityLevelAuthNoPriv:
+		client.MsgFlags = gosnmp.AuthNoPriv
+		usm.AuthenticationProtocol = authProtocols[strings.ToUpper(a.AuthProtocol)]
+		usm.AuthenticationPassphrase = a.AuthPassword
+	default:
+		client.MsgFlags = gosnmp.NoAuthNoPriv
+	}
+
+	client.Version = gosnmp.Version3
+	client.SecurityModel = gosnmp.UserSecurityModel
+	client.SecurityParameters = usm
+	client.ContextName = a.ContextName
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package tplinkddm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		auth    Auth
+		wantErr bool
+	}{
+		{"v2c", Auth{Version: 2, Community: "public"}, false},
+		{"default version", Auth{Community: "public"}, false},
+		{"v2c without community", Auth{Version: 2}, true},
+		{"unsupported version", Auth{Version: 1, Community: "public"}, true},
+		{"v3 noAuthNoPriv", Auth{Version: 3, Username: "user"}, false},
+		{"v3 without username", Auth{Version: 3}, true},
+		{"v3 authNoPriv", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthNoPriv,
+			AuthProtocol: "SHA256", AuthPassword: "authpass",
+		}, false},
+		{"v3 authNoPriv lowercase protocol", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthNoPriv,
+			AuthProtocol: "sha", AuthPassword: "authpass",
+		}, false},
+		{"v3 authNoPriv without password", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthNoPriv,
+			AuthProtocol: "SHA",
+		}, true},
+		{"v3 unknown auth protocol", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthNoPriv,
+			AuthProtocol: "CRC32", AuthPassword: "authpass",
+		}, true},
+		{"v3 authPriv", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA", AuthPassword: "authpass",
+			PrivProtocol: "AES", PrivPassword: "privpass",
+		}, false},
+		{"v3 authPriv without priv password", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA", AuthPassword: "authpass",
+			PrivProtocol: "AES",
+		}, true},
+		{"v3 authPriv unknown priv protocol", Auth{
+			Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA", AuthPassword: "authpass",
+			PrivProtocol: "ROT13", PrivPassword: "privpass",
+		}, true},
+		{"v3 unknown security level", Auth{Version: 3, Username: "user", SecurityLevel: "superSecure"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.auth.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAuth_Configure(t *testing.T) {
+	t.Parallel()
+
+	client := &gosnmp.GoSNMP{}
+	require.NoError(t, Auth{
+		Version: 3, Username: "user", SecurityLevel: SecurityLevelAuthPriv,
+		AuthProtocol: "SHA512", AuthPassword: "authpass",
+		PrivProtocol: "AES256C", PrivPassword: "privpass",
+		ContextName: "ctx",
+	}.configure(client))
+
+	assert.Equal(t, gosnmp.Version3, client.Version)
+	assert.Equal(t, gosnmp.AuthPriv, client.MsgFlags)
+	assert.Equal(t, gosnmp.UserSecurityModel, client.SecurityModel)
+	assert.Equal(t, "ctx", client.ContextName)
+
+	usm, ok := client.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.True(t, ok)
+	assert.Equal(t, "user", usm.UserName)
+	assert.Equal(t, gosnmp.SHA512, usm.AuthenticationProtocol)
+	assert.Equal(t, gosnmp.AES256C, usm.PrivacyProtocol)
+
+	assert.Error(t, Auth{Version: 3}.configure(&gosnmp.GoSNMP{}))
+}
+
+func TestGetDDMMetrics_Agent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		auth Auth
+	}{
+		{"v2c", Auth{Version: 2, Community: "s3cret"}},
+		{"v3 noAuthNoPriv", Auth{Version: 3, Username: "monitor"}},
+		{"v3 authNoPriv MD5", Auth{
+			Version: 3, Username: "monitor", SecurityLevel: SecurityLevelAuthNoPriv,
+			AuthProtocol: "MD5", AuthPassword: "authpassword",
+		}},
+		{"v3 authPriv SHA/DES", Auth{
+			Version: 3, Username: "monitor", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA", AuthPassword: "authpassword",
+			PrivProtocol: "DES", PrivPassword: "privpassword",
+		}},
+		{"v3 authPriv SHA256/AES", Auth{
+			Version: 3, Username: "monitor", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA256", AuthPassword: "authpassword",
+			PrivProtocol: "AES", PrivPassword: "privpassword",
+		}},
+		{"v3 authPriv SHA512/AES256", Auth{
+			Version: 3, Username: "monitor", SecurityLevel: SecurityLevelAuthPriv,
+			AuthProtocol: "SHA512", AuthPassword: "authpassword",
+			PrivProtocol: "AES256", PrivPassword: "privpassword",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var agent *testAgent
+			if tt.auth.Version == 3 {
+				agent = newTestAgentV3(t, tt.auth, testDDMVars())
+			} else {
+				agent = newTestAgent(t, tt.auth.Community, testDDMVars())
+			}
+
+			client := NewSNMPClientWithAuth("127.0.0.1", tt.auth)
+			client.port = agent.port
+
+			result, err := client.GetDDMMetrics(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, "agent-switch", result.SysName)
+			assert.Equal(t, 10, result.PDUs)
+			require.Len(t, result.Metrics, 2)
+			assert.Equal(t, "1", result.Metrics[0].Port)
+			assert.InDelta(t, 45.5, result.Metrics[0].Temperature, 0.01)
+			assert.InDelta(t, -4.2, result.Metrics[1].RxPower, 0.01)
+		})
+	}
+}
+
+func TestGetDDMMetrics_InvalidAuth(t *testing.T) {
+	t.Parallel()
+
+	client := NewSNMPClientWithAuth("127.0.0.1", Auth{Version: 3})
+
+	_, err := client.GetDDMMetrics(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	tplinkddm "github.com/hairyhenderson/tplink-ddm-exporter"
+	"gopkg.in/yaml.v3"
+)
+
+// discoverConfig holds the flags of the discover subcommand
+type discoverConfig struct {
+	ConfigFile string
+	Auth       string
+	Community  string
+	Output     string
+	LogLevel   string
+	Prefixes   []netip.Prefix
+	Timeout    time.Duration
+	Interval   time.Duration
+	Retries    int
+	Workers    int
+	Port       uint
+}
+
+func parseDiscoverFlags(fs *flag.FlagSet, args []string, cfg *discoverConfig) error {
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: %s discover [flags] CIDR...\n\n"+
+			"Sweeps the CIDRs for TP-Link switches with the DDM MIB, and writes them as a file_sd target file.\n\n",
+			filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.ConfigFile, "config.file", "", "Path to YAML configuration file to take -auth from")
+	fs.StringVar(&cfg.Auth, "auth", "", "Named auth from the configuration file to probe with, also written as the targets' __param_auth")
+	fs.StringVar(&cfg.Community, "community", "public", "SNMP community string, without -auth")
+	fs.StringVar(&cfg.Output, "output", "-", "File to write the targets to, as JSON, or YAML if it ends in .yml or .yaml (- for stdout)")
+	fs.DurationVar(&cfg.Timeout, "timeout", time.Second, "Time to wait for each address to answer")
+	fs.IntVar(&cfg.Retries, "retries", 0, "Number of retries for each address")
+	fs.IntVar(&cfg.Workers, "workers", 64, "Number of addresses to probe at once")
+	fs.UintVar(&cfg.Port, "port", 161, "SNMP port to probe")
+	fs.DurationVar(&cfg.Interval, "interval", 0,
+		"Sweep again on this interval, rewriting -output each time (0 sweeps once)")
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
+
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("parse flags: %w", err)
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("at least one CIDR to sweep is required")
+	}
+
+	for _, arg := range fs.Args() {
+		p, err := netip.ParsePrefix(arg)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR: %w", err)
+		}
+
+		cfg.Prefixes = append(cfg.Prefixes, p)
+	}
+
+	if cfg.Timeout <= 0 || cfg.Interval < 0 || cfg.Retries < 0 || cfg.Workers < 1 {
+		return errors.New("-timeout and -workers must be positive, and -interval and -retries not negative")
+	}
+
+	if cfg.Port == 0 || cfg.Port > 65535 {
+		return fmt.Errorf("invalid -port %d", cfg.Port)
+	}
+
+	if cfg.Interval > 0 && cfg.Output == "-" {
+		return errors.New("-interval needs an -output file")
+	}
+
+	return nil
+}
+
+// runDiscover runs the discover subcommand with the given arguments
+func runDiscover(ctx context.Context, args []string) error {
+	cfg := &discoverConfig{}
+	if err := parseDiscoverFlags(flag.NewFlagSet("discover", flag.ExitOnError), args, cfg); err != nil {
+		return err
+	}
+
+	slog.SetDefault(setupLogger(cfg.LogLevel))
+
+	auth := tplinkddm.Auth{Version: 2, Community: cfg.Community}
+
+	if cfg.Auth != "" {
+		if cfg.ConfigFile == "" {
+			return errors.New("-auth needs -config.file")
+		}
+
+		file, err := tplinkddm.LoadConfig(cfg.ConfigFile)
+		if err != nil {
+			return err
+		}
+
+		a, ok := file.Auth(cfg.Auth)
+		if !ok {
+			return fmt.Errorf("unknown auth %q", cfg.Auth)
+		}
+
+		auth = a
+	}
+
+	addrs, err := tplinkddm.SweepAddresses(cfg.Prefixes)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	newClient := func(host string) *tplinkddm.SNMPClient {
+		return tplinkddm.NewSNMPClientWithAuth(host, auth,
+			tplinkddm.WithPort(uint16(cfg.Port)), //nolint:gosec // checked by parseDiscoverFlags
+			tplinkddm.WithTimeout(cfg.Timeout),
+			tplinkddm.WithRetries(cfg.Retries),
+		)
+	}
+
+	for {
+		start := time.Now()
+		found := tplinkddm.Sweep(ctx, addrs, cfg.Workers, newClient)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		targets := tplinkddm.DiscoveredTargets(found, cfg.Auth)
+
+		slog.InfoContext(ctx, "sweep finished",
+			"addresses", len(addrs),
+			"tplink_devices", len(found),
+			"targets", len(targets),
+			"duration", time.Since(start))
+
+		for _, p := range found {
+			if !p.DDM {
+				slog.InfoContext(ctx, "skipping TP-Link device without the DDM MIB", "address", p.Address, "sys_name", p.SysName)
+			}
+		}
+
+		if err := writeTargets(cfg.Output, targets); err != nil {
+			return err
+		}
+
+		if cfg.Interval == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cfg.Interval):
+		}
+	}
+}
+
+// writeTargets writes the targets to path, or stdout for "-". Files are
+// replaced by renaming, so a file_sd watcher never sees a partial file.
+func writeTargets(path string, targets []tplinkddm.SDTargetGroup) error {
+	var (
+		b   []byte
+		err error
+	)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		b, err = yaml.Marshal(targets)
+	default:
+		b, err = json.MarshalIndent(targets, "", "  ")
+		b = append(b, '\n')
+	}
+
+	if err != nil {
+		return fmt.Errorf("encode targets: %w", err)
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+
+		return err //nolint:wrapcheck // nothing to add
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	// readable by Prometheus, which may run as another user
+	if err := tmp.Chmod(0o644); err != nil { //nolint:gosec // targets aren't secret
+		_ = tmp.Close()
+
+		return fmt.Errorf("chmod targets: %w", err)
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("write targets: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close targets: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename targets: %w", err)
+	}
+
+	return nil
+}
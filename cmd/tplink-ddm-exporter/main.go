@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,6 +12,10 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -23,16 +29,39 @@ import (
 )
 
 type config struct {
-	Target      string
-	Community   string
-	ListenAddr  string
-	LogLevel    string
-	showVersion bool
+	Target        string
+	Community     string
+	SecretFiles   secretFiles
+	ConfigFile    string
+	StateFile     string
+	Missing       string
+	EmptyCages    string
+	ListenAddr    string
+	LogLevel      string
+	V3            tplinkddm.Auth
+	SNMPVersion   int
+	PollInterval  time.Duration
+	CoalesceTTL   time.Duration
+	MaxWalks      int
+	MaxPerSwitch  int
+	TimeoutOffset time.Duration
+	DNSCacheTTL   time.Duration
+	URLSecrets    bool
+	showVersion   bool
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "discover" {
+		if err := runDiscover(context.Background(), os.Args[2:]); err != nil {
+			slog.Error("discover", "err", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	cfg := &config{}
-	if err := parseFlags(flag.CommandLine, cfg); err != nil {
+	if err := parseFlags(flag.CommandLine, os.Args[1:], cfg); err != nil {
 		slog.Error("parseFlags", "err", err)
 		os.Exit(1)
 	}
@@ -49,20 +78,171 @@ func main() {
 	}
 }
 
-func parseFlags(fs *flag.FlagSet, cfg *config) error {
-	fs.StringVar(&cfg.Target, "target", "192.168.2.96", "SNMP target IP address")
+func parseFlags(fs *flag.FlagSet, args []string, cfg *config) error {
+	fs.StringVar(&cfg.Target, "target", "192.168.2.96", "Default SNMP target address (host, host:port, tcp://host or [IPv6]:port)")
 	fs.StringVar(&cfg.Community, "community", "public", "SNMP community string")
+	fs.StringVar(&cfg.SecretFiles.Community, "community-file", "", "File to read the SNMP community string from, instead of -community")
+	fs.IntVar(&cfg.SNMPVersion, "snmp-version", 2, "Default SNMP version (2 or 3)")
+	fs.StringVar(&cfg.V3.Username, "username", "", "SNMPv3 USM username")
+	fs.StringVar(&cfg.V3.SecurityLevel, "security-level", tplinkddm.SecurityLevelAuthPriv,
+		"SNMPv3 security level (noAuthNoPriv, authNoPriv, authPriv)")
+	fs.StringVar(&cfg.V3.AuthProtocol, "auth-protocol", "SHA", "SNMPv3 auth protocol (MD5, SHA, SHA224, SHA256, SHA384, SHA512)")
+	fs.StringVar(&cfg.V3.AuthPassword, "auth-password", "", "SNMPv3 auth password")
+	fs.StringVar(&cfg.SecretFiles.AuthPassword, "auth-password-file", "", "File to read the SNMPv3 auth password from, instead of -auth-password")
+	fs.StringVar(&cfg.V3.PrivProtocol, "priv-protocol", "AES", "SNMPv3 priv protocol (DES, AES, AES192, AES256, AES192C, AES256C)")
+	fs.StringVar(&cfg.V3.PrivPassword, "priv-password", "", "SNMPv3 priv password")
+	fs.StringVar(&cfg.SecretFiles.PrivPassword, "priv-password-file", "", "File to read the SNMPv3 priv password from, instead of -priv-password")
+	fs.StringVar(&cfg.V3.ContextName, "context-name", "", "SNMPv3 context name")
+	fs.StringVar(&cfg.ConfigFile, "config.file", "", "Path to YAML configuration file with auths and targets")
+	fs.StringVar(&cfg.StateFile, "sfp.state-file", "", "Path to a JSON file persisting the last SFP module seen in each port (in memory only if empty)")
+	fs.StringVar(&cfg.Missing, "missing-values", "omit", "How to export DDM readings the switch didn't report or that couldn't be parsed (omit or nan)")
+	fs.StringVar(&cfg.EmptyCages, "empty-cages", tplinkddm.EmptyCagesFlag,
+		"How to export SFP ports with no transceiver (flag: tplink_sfp_present=0 and the port and interface "+
+			"info, status and speed, but no readings; skip: nothing; all: every series)")
+	fs.DurationVar(&cfg.PollInterval, "poll.interval", 0,
+		"Walk the config file's targets in the background on this interval, serving scrapes from the cached results (0 walks on every scrape)")
+	fs.DurationVar(&cfg.CoalesceTTL, "scrape.coalesce-ttl", 0,
+		"Reuse a target's walk for scrapes arriving this long after it finished (concurrent scrapes always share one walk)")
+	fs.DurationVar(&cfg.TimeoutOffset, "scrape.timeout-offset", 500*time.Millisecond,
+		"Subtracted from Prometheus' scrape timeout (X-Prometheus-Scrape-Timeout-Seconds) to give the walk's deadline")
+	fs.IntVar(&cfg.MaxWalks, "snmp.max-concurrent", 0, "Maximum SNMP walks running at once, further scrapes queue (0 for no limit)")
+	fs.IntVar(&cfg.MaxPerSwitch, "snmp.max-concurrent-per-target", 0,
+		"Maximum SNMP walks of any one switch running at once, further scrapes queue (0 for no limit)")
+	fs.DurationVar(&cfg.DNSCacheTTL, "dns.cache-ttl", time.Minute,
+		"How long to cache DNS answers for hostname targets and target groups")
+	fs.BoolVar(&cfg.URLSecrets, "scrape.allow-url-credentials", true,
+		"Allow the SNMP community in the /scrape query string (deprecated, will default to false in the next major version)")
 	fs.StringVar(&cfg.ListenAddr, "addr", ":9116", "Listen address")
 	fs.StringVar(&cfg.LogLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	fs.BoolVar(&cfg.showVersion, "version", false, "Show version and exit")
 
-	if err := fs.Parse(os.Args[1:]); err != nil {
+	if err := fs.Parse(args); err != nil {
 		return fmt.Errorf("parse flags: %w", err)
 	}
 
+	cfg.V3.Version = 3
+
+	if err := cfg.SecretFiles.load(fs, cfg); err != nil {
+		return err
+	}
+
+	if cfg.Missing != "omit" && cfg.Missing != "nan" {
+		return fmt.Errorf("unsupported -missing-values %q (want omit or nan)", cfg.Missing)
+	}
+
+	if cfg.PollInterval < 0 {
+		return errors.New("-poll.interval must not be negative")
+	}
+
+	if cfg.CoalesceTTL < 0 {
+		return errors.New("-scrape.coalesce-ttl must not be negative")
+	}
+
+	if cfg.TimeoutOffset < 0 {
+		return errors.New("-scrape.timeout-offset must not be negative")
+	}
+
+	if cfg.DNSCacheTTL < 0 {
+		return errors.New("-dns.cache-ttl must not be negative")
+	}
+
+	if cfg.MaxWalks < 0 || cfg.MaxPerSwitch < 0 {
+		return errors.New("-snmp.max-concurrent and -snmp.max-concurrent-per-target must not be negative")
+	}
+
+	if !tplinkddm.ValidEmptyCages(cfg.EmptyCages) {
+		return fmt.Errorf("unsupported -empty-cages %q (want flag, skip or all)", cfg.EmptyCages)
+	}
+
+	if cfg.SNMPVersion != 2 && cfg.SNMPVersion != 3 {
+		return fmt.Errorf("unsupported SNMP version %d", cfg.SNMPVersion)
+	}
+
+	if cfg.SNMPVersion == 3 {
+		if err := cfg.V3.Validate(); err != nil {
+			return fmt.Errorf("SNMPv3 flags: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// secretFiles holds the paths of the -*-file flags, which keep secrets out of
+// the command line, where any user can see them with ps
+type secretFiles struct {
+	Community    string
+	AuthPassword string
+	PrivPassword string
+}
+
+// load reads each secret file into the flag it replaces. Setting both forms
+// of a flag is an error.
+func (s secretFiles) load(fs *flag.FlagSet, cfg *config) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for _, secret := range []struct {
+		flag  string
+		file  string
+		value *string
+	}{
+		{"community", s.Community, &cfg.Community},
+		{"auth-password", s.AuthPassword, &cfg.V3.AuthPassword},
+		{"priv-password", s.PrivPassword, &cfg.V3.PrivPassword},
+	} {
+		if secret.file == "" {
+			continue
+		}
+
+		if set[secret.flag] {
+			return fmt.Errorf("only one of -%[1]s and -%[1]s-file may be set", secret.flag)
+		}
+
+		b, err := os.ReadFile(secret.file)
+		if err != nil {
+			return fmt.Errorf("read -%s-file: %w", secret.flag, err)
+		}
+
+		*secret.value = strings.TrimRight(string(b), "\r\n")
+	}
+
+	return nil
+}
+
+// auth returns the named auth profile. Profiles from the configuration file
+// take precedence over the built-in ones: "v2c" uses the given community,
+// "v3" uses the USM credentials from the command-line flags. An empty name
+// selects the default -snmp-version.
+func (c *config) auth(file *tplinkddm.Config, name, community string) (tplinkddm.Auth, error) {
+	if auth, ok := file.Auth(name); ok {
+		return auth, nil
+	}
+
+	if name == "" {
+		name = "v2c"
+		if c.SNMPVersion == 3 {
+			name = "v3"
+		}
+	}
+
+	switch name {
+	case "v2c":
+		if community == "" {
+			community = c.Community
+		}
+
+		return tplinkddm.Auth{Version: 2, Community: community}, nil
+	case "v3":
+		if err := c.V3.Validate(); err != nil {
+			return tplinkddm.Auth{}, fmt.Errorf("v3 auth: %w", err)
+		}
+
+		return c.V3, nil
+	default:
+		return tplinkddm.Auth{}, fmt.Errorf("unknown auth %q", name)
+	}
+}
+
 func run(ctx context.Context, cfg *config) error {
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
@@ -87,15 +267,120 @@ func run(ctx context.Context, cfg *config) error {
 		}(ctx)
 	}
 
+	reloader, err := tplinkddm.NewConfigReloader(cfg.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	// targets from the config file's file_sd_configs are followed as the
+	// files change, and as the config is reloaded
+	discovery := tplinkddm.NewFileDiscovery()
+
+	reloader.SetFileDiscovery(discovery)
+
+	go discovery.Run(ctx)
+
+	go reloadOnSIGHUP(ctx, logger, reloader)
+
+	modules, err := tplinkddm.NewModuleTracker(cfg.StateFile)
+	if err != nil {
+		return fmt.Errorf("load SFP module state: %w", err)
+	}
+
 	logger.InfoContext(ctx, "starting TP-Link DDM exporter",
 		"default_target", cfg.Target,
-		"listen_addr", cfg.ListenAddr)
+		"config_file", cfg.ConfigFile,
+		"listen_addr", cfg.ListenAddr,
+		"poll_interval", cfg.PollInterval)
+
+	state := &scrapeState{
+		modules:     modules,
+		parseErrors: tplinkddm.NewParseErrorCounts(),
+		coalescer:   tplinkddm.NewCoalescer(cfg.CoalesceTTL),
+		limiter:     tplinkddm.NewLimiter(cfg.MaxWalks, cfg.MaxPerSwitch),
+		resolver:    tplinkddm.NewResolver(cfg.DNSCacheTTL),
+		discovery:   discovery,
+	}
 
-	srv := setupServer(ctx, cfg)
+	if cfg.PollInterval > 0 {
+		state.poller = tplinkddm.NewPoller(cfg.PollInterval, func() []tplinkddm.PollTarget {
+			return cfg.pollTargets(ctx, reloader.Config(), state)
+		})
+
+		go state.poller.Run(ctx)
+	}
+
+	srv := setupServer(ctx, cfg, reloader, state)
 
 	return serve(ctx, logger, srv, cfg.ListenAddr, stop)
 }
 
+// scrapeState is the state shared between scrapes
+type scrapeState struct {
+	modules     *tplinkddm.ModuleTracker
+	parseErrors *tplinkddm.ParseErrorCounts
+	poller      *tplinkddm.Poller // nil unless polling
+	coalescer   *tplinkddm.Coalescer
+	limiter     *tplinkddm.Limiter
+	resolver    *tplinkddm.Resolver
+	discovery   *tplinkddm.FileDiscovery
+}
+
+// pollTargets returns the targets from the configuration file, including
+// those listed in DNS by its target groups, for the poller, each with an SNMP
+// client using its configured auth
+func (c *config) pollTargets(ctx context.Context, file *tplinkddm.Config, state *scrapeState) []tplinkddm.PollTarget {
+	if file == nil {
+		return nil
+	}
+
+	resolved := file.ResolveTargets(ctx, state.resolver)
+	targets := make([]tplinkddm.PollTarget, 0, len(resolved))
+
+	for _, tc := range resolved {
+		auth, err := c.auth(file, tc.Auth, "")
+		if err != nil {
+			slog.Error("not polling target", "target", tc.Name, "err", err)
+
+			continue
+		}
+
+		client, err := tc.NewClient(auth, tplinkddm.WithResolver(state.resolver))
+		if err != nil {
+			slog.Error("not polling target", "target", tc.Name, "err", err)
+
+			continue
+		}
+
+		targets = append(targets, tplinkddm.PollTarget{
+			Name:   tc.Name,
+			Client: state.limiter.Getter(tc.Address, client),
+		})
+	}
+
+	return targets
+}
+
+// reloadOnSIGHUP reloads the configuration file whenever SIGHUP is received,
+// until ctx is done.
+func reloadOnSIGHUP(ctx context.Context, logger *slog.Logger, reloader *tplinkddm.ConfigReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := reloader.Reload(); err != nil {
+				logger.ErrorContext(ctx, "failed to reload config, keeping previous config", "err", err)
+			}
+		}
+	}
+}
+
 func serve(ctx context.Context, logger *slog.Logger, srv *http.Server, listenAddr string, stop func()) error {
 	lc := &net.ListenConfig{}
 
@@ -129,17 +414,25 @@ func serve(ctx context.Context, logger *slog.Logger, srv *http.Server, listenAdd
 	return nil
 }
 
-func setupServer(ctx context.Context, cfg *config) *http.Server {
+func setupServer(ctx context.Context, cfg *config, reloader *tplinkddm.ConfigReloader, state *scrapeState) *http.Server {
 	mux := http.NewServeMux()
 
 	exporterRegistry := prometheus.NewRegistry()
 	exporterRegistry.MustRegister(collectors.NewGoCollector())
 	exporterRegistry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	exporterRegistry.MustRegister(reloader)
+	exporterRegistry.MustRegister(state.coalescer)
+	exporterRegistry.MustRegister(state.limiter)
+	exporterRegistry.MustRegister(state.resolver)
+	exporterRegistry.MustRegister(state.discovery)
 
 	mux.Handle("/metrics", promhttp.HandlerFor(exporterRegistry, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
 	}))
-	mux.Handle("/scrape", otelhttp.NewHandler(scrapeHandler(cfg), "GET /scrape"))
+	mux.Handle("/scrape", urlCredentials(cfg.URLSecrets,
+		otelhttp.NewHandler(scrapeHandler(cfg, reloader, state), "GET /scrape")))
+	mux.Handle("/sd", sdHandler(reloader, state.resolver))
+	mux.Handle("/-/reload", reloadHandler(reloader))
 	mux.HandleFunc("/", rootHandler)
 
 	return &http.Server{
@@ -150,20 +443,57 @@ func setupServer(ctx context.Context, cfg *config) *http.Server {
 	}
 }
 
-func scrapeHandler(cfg *config) http.HandlerFunc {
+func scrapeHandler(cfg *config, reloader *tplinkddm.ConfigReloader, state *scrapeState) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		target := r.URL.Query().Get("target")
-		if target == "" {
-			target = cfg.Target
+		ctx, cancel, err := scrapeContext(r, cfg.TimeoutOffset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
 		}
 
-		community := r.URL.Query().Get("community")
-		if community == "" {
-			community = cfg.Community
+		defer cancel()
+
+		// take one snapshot of the config, so a concurrent reload can't
+		// change it mid-scrape
+		fileCfg := reloader.Config()
+
+		targets := r.URL.Query()["target"]
+		group := r.URL.Query().Get("group")
+
+		if group == "" && len(targets) <= 1 {
+			target := cfg.Target
+			if len(targets) == 1 && targets[0] != "" {
+				target = targets[0]
+			}
+
+			collector, tc, err := cfg.targetCollector(ctx, r, fileCfg, state, target)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			scrapeRegistry := prometheus.NewRegistry()
+			prometheus.WrapRegistererWith(tc.Labels, scrapeRegistry).MustRegister(collector)
+
+			promhttp.HandlerFor(scrapeRegistry, promhttp.HandlerOpts{
+				EnableOpenMetrics: true,
+			}).ServeHTTP(w, r)
+
+			return
+		}
+
+		targets, err = scrapeTargets(ctx, fileCfg, state.resolver, targets, group)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
 		}
 
-		snmpClient := tplinkddm.NewSNMPClient(target, community)
-		collector := tplinkddm.NewCollector(snmpClient, target).WithContext(r.Context())
+		collector := multiTargetCollector(ctx, targets, func(target string) (*tplinkddm.Collector, tplinkddm.TargetConfig, error) {
+			return cfg.targetCollector(ctx, r, fileCfg, state, target)
+		})
 
 		scrapeRegistry := prometheus.NewRegistry()
 		scrapeRegistry.MustRegister(collector)
@@ -174,6 +504,235 @@ func scrapeHandler(cfg *config) http.HandlerFunc {
 	}
 }
 
+// scrapeTargets returns the targets of a multi-target scrape: the target
+// parameters followed by the members of group, if given, without blanks or
+// repeats. A group with no members is an error.
+func scrapeTargets(ctx context.Context, fileCfg *tplinkddm.Config, resolver *tplinkddm.Resolver,
+	targets []string, group string,
+) ([]string, error) {
+	if group != "" {
+		members := fileCfg.GroupTargets(ctx, resolver, group)
+		if len(members) == 0 {
+			return nil, fmt.Errorf("no targets in group %q", group)
+		}
+
+		targets = slices.Clone(targets)
+		for _, tc := range members {
+			targets = append(targets, tc.Name)
+		}
+	}
+
+	unique := make([]string, 0, len(targets))
+	seen := map[string]bool{}
+
+	for _, target := range targets {
+		if target != "" && !seen[target] {
+			seen[target] = true
+
+			unique = append(unique, target)
+		}
+	}
+
+	return unique, nil
+}
+
+// multiTargetCollector returns a collector walking each target concurrently,
+// with the collector newCollector returns for it and its configured labels.
+// Each target reports its own up and error metrics, so one bad switch doesn't
+// fail the others: a target newCollector fails for is reported as down.
+func multiTargetCollector(ctx context.Context, targets []string,
+	newCollector func(target string) (*tplinkddm.Collector, tplinkddm.TargetConfig, error),
+) prometheus.Collector {
+	collectors := make([]prometheus.Collector, 0, len(targets))
+
+	for _, target := range targets {
+		collector, tc, err := newCollector(target)
+		if err != nil {
+			slog.WarnContext(ctx, "can't scrape target", "target", target, "err", err)
+
+			collector = tplinkddm.NewCollector(failedGetter{err: err}, target).WithContext(ctx)
+		}
+
+		collectors = append(collectors, prometheus.WrapCollectorWith(tc.Labels, collector))
+	}
+
+	return tplinkddm.NewMultiCollector(collectors...)
+}
+
+// targetCollector returns the collector for one target of a scrape, and the
+// target's configuration, whose labels are to be added to its metrics
+func (c *config) targetCollector(ctx context.Context, r *http.Request, fileCfg *tplinkddm.Config,
+	state *scrapeState, target string,
+) (*tplinkddm.Collector, tplinkddm.TargetConfig, error) {
+	// targets listed in the config file, directly or through a DNS target
+	// group, bring their own address, auth, client settings and extra labels
+	tc, configured := fileCfg.LookupTarget(ctx, state.resolver, target)
+	if !configured {
+		tc = tplinkddm.TargetConfig{Name: target, Address: target}
+	}
+
+	authName := r.URL.Query().Get("auth")
+	if authName == "" {
+		authName = tc.Auth
+	}
+
+	auth, err := c.auth(fileCfg, authName, r.URL.Query().Get("community"))
+	if err != nil {
+		return nil, tc, fmt.Errorf("%w: %w", tplinkddm.ErrInvalidAuth, err)
+	}
+
+	emptyCages := c.EmptyCages
+	if tc.EmptyCages != "" {
+		emptyCages = tc.EmptyCages
+	}
+
+	client, err := tc.NewClient(auth, tplinkddm.WithResolver(state.resolver))
+	if err != nil {
+		return nil, tc, err
+	}
+
+	// concurrent scrapes of the same target share one walk, which waits for
+	// a free slot under the concurrency limits
+	snmpClient := state.coalescer.Getter(target, auth, state.limiter.Getter(tc.Address, client))
+
+	// polled targets are served from the cache, unless the request asks for
+	// different credentials than the poller uses
+	if r.URL.Query().Get("auth") == "" && r.URL.Query().Get("community") == "" {
+		if cached, ok := state.poller.Getter(target); ok {
+			snmpClient = cached
+		}
+	}
+
+	opts := []tplinkddm.CollectorOption{
+		tplinkddm.WithMissingAsNaN(c.Missing == "nan"),
+		tplinkddm.WithEmptyCages(emptyCages),
+	}
+
+	// state kept across scrapes (and in the state file) is only kept for
+	// configured targets, so arbitrary targets in the query string can't
+	// grow it without bound
+	if configured || target == c.Target {
+		opts = append(opts,
+			tplinkddm.WithModuleTracker(state.modules),
+			tplinkddm.WithParseErrorCounts(state.parseErrors),
+		)
+	}
+
+	collector := tplinkddm.NewCollector(snmpClient, target, opts...).WithContext(ctx)
+
+	return collector, tc, nil
+}
+
+// failedGetter reports a target which couldn't be set up for a multi-target
+// scrape as a failed walk, so it shows up as tplink_ddm_up 0
+type failedGetter struct {
+	err error
+}
+
+func (g failedGetter) GetDDMMetrics(context.Context) (*tplinkddm.DDMResult, error) {
+	return nil, g.err
+}
+
+// scrapeContext returns the request's context with a deadline taken from
+// Prometheus' X-Prometheus-Scrape-Timeout-Seconds header, less offset, so the
+// walk gives up before Prometheus does. Without the header, the request's
+// context is used as is.
+func scrapeContext(r *http.Request, offset time.Duration) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return r.Context(), func() {}, nil
+	}
+
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return nil, nil, fmt.Errorf("invalid X-Prometheus-Scrape-Timeout-Seconds %q", header)
+	}
+
+	timeout := time.Duration(seconds * float64(time.Second))
+
+	// an offset as long as the timeout itself would leave no time at all
+	if timeout > offset {
+		timeout -= offset
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+
+	return ctx, cancel, nil
+}
+
+func reloadHandler(reloader *tplinkddm.ConfigReloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "This endpoint requires a POST or PUT request.", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		if err := reloader.Reload(); err != nil {
+			slog.ErrorContext(r.Context(), "failed to reload config, keeping previous config", "err", err)
+			http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+
+			return
+		}
+
+		_, _ = io.WriteString(w, "OK\n")
+	}
+}
+
+// urlCredentialsParams are the /scrape query parameters carrying secrets
+//
+//nolint:gochecknoglobals // lookup table
+var urlCredentialsParams = []string{"community"}
+
+// warnURLCredentials logs the deprecation of URL credentials once
+//
+//nolint:gochecknoglobals // once per process
+var warnURLCredentials sync.Once
+
+// urlCredentials guards next against credentials in the query string. When
+// they're not allowed, such requests are rejected before reaching next, so
+// the secrets don't end up in its trace spans; otherwise their use is
+// logged once as deprecated.
+func urlCredentials(allow bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, param := range urlCredentialsParams {
+			if !r.URL.Query().Has(param) {
+				continue
+			}
+
+			if !allow {
+				http.Error(w, fmt.Sprintf("credentials in the URL (%s) are not allowed, "+
+					"reference a named auth from the configuration file with auth= instead", param),
+					http.StatusBadRequest)
+
+				return
+			}
+
+			warnURLCredentials.Do(func() {
+				slog.WarnContext(r.Context(), "credentials in the /scrape URL are deprecated, "+
+					"use named auths and -scrape.allow-url-credentials=false", "param", param)
+			})
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sdHandler serves the configured targets in Prometheus' HTTP service
+// discovery format, for http_sd_configs
+func sdHandler(reloader *tplinkddm.ConfigReloader, resolver *tplinkddm.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups := reloader.Config().ServiceDiscovery(r.Context(), resolver)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(groups); err != nil {
+			slog.ErrorContext(r.Context(), "failed to write service discovery response", "err", err)
+		}
+	}
+}
+
 func rootHandler(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/html")
 	_, _ = io.WriteString(w, `<html>
@@ -182,13 +741,19 @@ func rootHandler(w http.ResponseWriter, _ *http.Request) {
 <h1>TP-Link DDM Exporter</h1>
 <p><a href="/metrics">Exporter Metrics</a></p>
 <p><a href="/scrape">Scrape Device Metrics (default target)</a></p>
+<p><a href="/sd">Service Discovery (configured targets)</a></p>
 <h2>Usage</h2>
 <p>Scrape a specific device:</p>
 <pre>/scrape?target=192.168.1.100</pre>
+<p>Scrape several devices, or a configured group, in one request:</p>
+<pre>/scrape?target=192.168.1.100&amp;target=192.168.1.101
+/scrape?group=site-a</pre>
 <p>Query parameters:</p>
 <ul>
-<li><code>target</code> - SNMP target IP address (defaults to configured target)</li>
-<li><code>community</code> - SNMP community string (defaults to configured community)</li>
+<li><code>target</code> - SNMP target address, e.g. <code>192.168.1.100</code>, <code>switch:1161</code>, <code>tcp://[2001:db8::1]</code> (defaults to configured target); may be repeated</li>
+<li><code>group</code> - Scrape every configured target in this group</li>
+<li><code>auth</code> - Auth profile from the config file, or <code>v2c</code>/<code>v3</code> (defaults to the target's configured auth, then the configured SNMP version)</li>
+<li><code>community</code> - SNMP community string for <code>v2c</code> (defaults to configured community; deprecated, rejected with <code>-scrape.allow-url-credentials=false</code>)</li>
 </ul>
 <p>Device names are automatically detected from SNMP sysName.</p>
 </body>
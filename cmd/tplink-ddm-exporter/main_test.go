@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tplinkddm "github.com/hairyhenderson/tplink-ddm-exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestURLCredentials(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		url    string
+		allow  bool
+		status int
+		spans  int
+	}{
+		{"rejected", "/scrape?target=10.0.0.1&community=secret", false, http.StatusBadRequest, 0},
+		{"rejected empty", "/scrape?target=10.0.0.1&community=", false, http.StatusBadRequest, 0},
+		{"allowed", "/scrape?target=10.0.0.1&community=secret", true, http.StatusOK, 1},
+		{"no credentials", "/scrape?target=10.0.0.1&auth=v3", false, http.StatusOK, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+			reached := false
+			next := otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				reached = true
+
+				_, _ = io.WriteString(w, "ok")
+			}), "/scrape", otelhttp.WithTracerProvider(tp))
+
+			rec := httptest.NewRecorder()
+			urlCredentials(tt.allow, next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.status == http.StatusOK, reached)
+
+			// a rejected request never gets a span, so the community can't
+			// leak into traces
+			assert.Len(t, spans.Ended(), tt.spans)
+		})
+	}
+}
+
+func TestParseFlags_SecretFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	community := write("community", "s3cret\n")
+	authPass := write("auth", "authpass123\n")
+	privPass := write("priv", "privpass123")
+
+	cfg := &config{}
+	require.NoError(t, parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"-community-file", community,
+		"-snmp-version", "3", "-username", "monitor",
+		"-auth-password-file", authPass, "-priv-password-file", privPass,
+	}, cfg))
+	assert.Equal(t, "s3cret", cfg.Community)
+	assert.Equal(t, "authpass123", cfg.V3.AuthPassword)
+	assert.Equal(t, "privpass123", cfg.V3.PrivPassword)
+
+	cfg = &config{}
+	require.NoError(t, parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), nil, cfg))
+	assert.Equal(t, "public", cfg.Community)
+
+	for name, args := range map[string][]string{
+		"both forms":   {"-community", "public", "-community-file", community},
+		"missing file": {"-community-file", filepath.Join(dir, "nope")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+
+			assert.Error(t, parseFlags(fs, args, &config{}))
+		})
+	}
+}
+
+// staticGetter returns a fixed walk result
+type staticGetter struct {
+	result *tplinkddm.DDMResult
+}
+
+func (g staticGetter) GetDDMMetrics(context.Context) (*tplinkddm.DDMResult, error) {
+	return g.result, nil
+}
+
+func loadTestConfig(t *testing.T, content string) *tplinkddm.Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := tplinkddm.LoadConfig(path)
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestScrapeTargets(t *testing.T) {
+	fileCfg := loadTestConfig(t, `
+targets:
+  - name: sw1
+    address: 10.0.0.1
+    groups: [ams1]
+  - name: sw2
+    address: 10.0.0.2
+    groups: [ams1, core]
+  - name: sw3
+    address: 10.0.0.3
+`)
+
+	targets, err := scrapeTargets(t.Context(), fileCfg, nil, []string{"sw3", "", "sw3"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sw3"}, targets)
+
+	// group members follow the target parameters, once each
+	params := []string{"sw2", "10.0.0.9"}
+	targets, err = scrapeTargets(t.Context(), fileCfg, nil, params, "ams1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sw2", "10.0.0.9", "sw1"}, targets)
+	assert.Equal(t, []string{"sw2", "10.0.0.9"}, params)
+
+	_, err = scrapeTargets(t.Context(), fileCfg, nil, nil, "nope")
+	require.Error(t, err)
+
+	_, err = scrapeTargets(t.Context(), nil, nil, nil, "ams1")
+	assert.Error(t, err)
+}
+
+func TestMultiTargetCollector(t *testing.T) {
+	result := &tplinkddm.DDMResult{
+		SysName: "sw",
+		Metrics: []tplinkddm.DDMMetrics{{Port: "1", Interface: "1/0/1", Temperature: 40}},
+	}
+
+	var called []string
+
+	collector := multiTargetCollector(t.Context(), []string{"sw1", "bad", "sw2"},
+		func(target string) (*tplinkddm.Collector, tplinkddm.TargetConfig, error) {
+			called = append(called, target)
+
+			tc := tplinkddm.TargetConfig{Name: target, Labels: map[string]string{"site": target + "-site"}}
+			if target == "bad" {
+				return nil, tc, errors.New("unknown auth")
+			}
+
+			return tplinkddm.NewCollector(staticGetter{result}, target), tc, nil
+		})
+
+	assert.Equal(t, []string{"sw1", "bad", "sw2"}, called)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(collector))
+
+	// the target that failed to set up is down, with its labels, and
+	// doesn't take the others down with it
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP tplink_ddm_up Whether the last scrape of the switch succeeded (1 = success, 0 = failure)
+# TYPE tplink_ddm_up gauge
+tplink_ddm_up{site="bad-site",target="bad"} 0
+tplink_ddm_up{site="sw1-site",target="sw1"} 1
+tplink_ddm_up{site="sw2-site",target="sw2"} 1
+`), "tplink_ddm_up")
+	require.NoError(t, err)
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP tplink_sfp_temperature_celsius SFP temperature in Celsius
+# TYPE tplink_sfp_temperature_celsius gauge
+tplink_sfp_temperature_celsius{device="sw",interface="1/0/1",port="1",site="sw1-site",target="sw1"} 40
+tplink_sfp_temperature_celsius{device="sw",interface="1/0/1",port="1",site="sw2-site",target="sw2"} 40
+`), "tplink_sfp_temperature_celsius")
+	assert.NoError(t, err)
+}
+
+func TestScrapeHandler_Group(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+targets:
+  - name: sw1
+    address: 127.0.0.1
+    groups: [ams1]
+`), 0o600))
+
+	reloader, err := tplinkddm.NewConfigReloader(path)
+	require.NoError(t, err)
+
+	modules, err := tplinkddm.NewModuleTracker("")
+	require.NoError(t, err)
+
+	cfg := &config{Target: "127.0.0.1", Community: "public", SNMPVersion: 2, EmptyCages: tplinkddm.EmptyCagesFlag}
+	state := &scrapeState{
+		modules:     modules,
+		parseErrors: tplinkddm.NewParseErrorCounts(),
+		coalescer:   tplinkddm.NewCoalescer(0),
+		limiter:     tplinkddm.NewLimiter(0, 0),
+		resolver:    tplinkddm.NewResolver(0),
+	}
+
+	srv := httptest.NewServer(scrapeHandler(cfg, reloader, state))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "?group=nope")
+	require.NoError(t, err)
+
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// an unknown auth fails the group's members, which are reported as down
+	// targets rather than failing the request
+	resp, err = http.Get(srv.URL + "?group=ams1&target=10.0.0.9&auth=nope")
+	require.NoError(t, err)
+
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body := new(strings.Builder)
+	_, err = io.Copy(body, resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, body.String(), `tplink_ddm_up{target="sw1"} 0`)
+	assert.Contains(t, body.String(), `tplink_ddm_up{target="10.0.0.9"} 0`)
+}
@@ -0,0 +1,215 @@
+package tplinkddm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Coalescer de-duplicates concurrent walks of the same switch: a scrape
+// arriving while another scrape of the same target is walking it waits for,
+// and shares, that walk's result. With a TTL, a successful result is also
+// reused by scrapes arriving within the TTL after the walk finished.
+type Coalescer struct {
+	calls     map[coalesceKey]*coalescedCall
+	coalesced prometheus.Counter
+	ttl       time.Duration
+	mu        sync.Mutex
+}
+
+// coalesceKey identifies walks which can share a result. Scrapes of the same
+// target with different credentials are kept apart.
+type coalesceKey struct {
+	target string
+	auth   Auth
+}
+
+// coalescedCall is a walk in progress, or a finished one within its TTL
+type coalescedCall struct {
+	ctx    *sharedContext
+	done   chan struct{}
+	result *DDMResult
+	err    error
+}
+
+// NewCoalescer creates a Coalescer, reusing successful results for ttl after
+// each walk (0 only shares walks which are still in progress)
+func NewCoalescer(ttl time.Duration) *Coalescer {
+	return &Coalescer{
+		calls: map[coalesceKey]*coalescedCall{},
+		// not labelled by target, as targets come from the query string
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "tplink_ddm_exporter_coalesced_scrapes_total",
+			Help: "Number of scrapes served by sharing another scrape's walk of the same target",
+		}),
+		ttl: ttl,
+	}
+}
+
+// Getter wraps client, so that concurrent walks of target with the same auth
+// share one call to client
+func (c *Coalescer) Getter(target string, auth Auth, client SNMPGetter) SNMPGetter {
+	return &coalescedGetter{
+		coalescer: c,
+		client:    client,
+		key:       coalesceKey{target: target, auth: auth},
+	}
+}
+
+type coalescedGetter struct {
+	coalescer *Coalescer
+	client    SNMPGetter
+	key       coalesceKey
+}
+
+// GetDDMMetrics implements SNMPGetter
+func (g *coalescedGetter) GetDDMMetrics(ctx context.Context) (*DDMResult, error) {
+	return g.coalescer.do(ctx, g.key, g.client)
+}
+
+func (c *Coalescer) do(ctx context.Context, key coalesceKey, client SNMPGetter) (*DDMResult, error) {
+	c.mu.Lock()
+
+	call, ok := c.calls[key]
+	if ok {
+		c.coalesced.Inc()
+		call.ctx.extend(ctx)
+	} else {
+		call = &coalescedCall{ctx: newSharedContext(ctx), done: make(chan struct{})}
+		c.calls[key] = call
+
+		go c.walk(key, call, client)
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for coalesced walk: %w", ctx.Err())
+	}
+}
+
+// walk runs a shared walk, and then forgets it, immediately or after the TTL
+func (c *Coalescer) walk(key coalesceKey, call *coalescedCall, client SNMPGetter) {
+	call.result, call.err = client.GetDDMMetrics(call.ctx)
+	call.ctx.stop()
+	close(call.done)
+
+	if c.ttl > 0 && call.err == nil {
+		time.AfterFunc(c.ttl, func() { c.forget(key, call) })
+	} else {
+		c.forget(key, call)
+	}
+}
+
+// sharedContext is the context of a shared walk. One scraper going away
+// mustn't fail the walk for the others, so it isn't cancelled with any
+// scrape's context. Its deadline is the latest of the waiting scrapes', so
+// a scrape joining with more time left isn't cut short by the scrape which
+// started the walk.
+type sharedContext struct {
+	context.Context //nolint:containedctx // the walk's context, extended by joining scrapes
+
+	deadline time.Time
+	timer    *time.Timer
+	cancel   context.CancelCauseFunc
+	mu       sync.Mutex
+}
+
+// newSharedContext returns a shared walk context, with ctx's values and
+// deadline
+func newSharedContext(ctx context.Context) *sharedContext {
+	base, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	s := &sharedContext{Context: base, cancel: cancel}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.deadline = deadline
+		s.timer = time.AfterFunc(time.Until(deadline), func() { cancel(context.DeadlineExceeded) })
+	}
+
+	return s
+}
+
+// extend pushes the deadline back to ctx's, if that's later. A ctx without a
+// deadline removes it. It has no effect once the deadline has passed.
+func (s *sharedContext) extend(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer == nil {
+		return
+	}
+
+	deadline, ok := ctx.Deadline()
+	if ok && !deadline.After(s.deadline) {
+		return
+	}
+
+	if !s.timer.Stop() {
+		return
+	}
+
+	if !ok {
+		s.timer, s.deadline = nil, time.Time{}
+
+		return
+	}
+
+	s.deadline = deadline
+	s.timer.Reset(time.Until(deadline))
+}
+
+// stop releases the context's resources once the walk is done
+func (s *sharedContext) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+
+	s.cancel(context.Canceled)
+}
+
+// Deadline implements context.Context, returning the current deadline
+func (s *sharedContext) Deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deadline, s.timer != nil
+}
+
+// Err implements context.Context, reporting context.DeadlineExceeded once
+// the deadline has passed
+func (s *sharedContext) Err() error {
+	if s.Context.Err() == nil {
+		return nil
+	}
+
+	return context.Cause(s.Context)
+}
+
+// forget removes a finished call, unless it has already been replaced
+func (c *Coalescer) forget(key coalesceKey, call *coalescedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Coalescer) Describe(ch chan<- *prometheus.Desc) {
+	c.coalesced.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (c *Coalescer) Collect(ch chan<- prometheus.Metric) {
+	c.coalesced.Collect(ch)
+}
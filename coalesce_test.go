@@ -0,0 +1,220 @@
+package tplinkddm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingClient counts walks, each of which waits for release
+type blockingClient struct {
+	release chan struct{}
+	result  *DDMResult
+	mu      sync.Mutex
+	walks   int
+}
+
+func (c *blockingClient) GetDDMMetrics(ctx context.Context) (*DDMResult, error) {
+	c.mu.Lock()
+	c.walks++
+	c.mu.Unlock()
+
+	select {
+	case <-c.release:
+		return c.result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *blockingClient) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.walks
+}
+
+func TestCoalescer_InFlight(t *testing.T) {
+	c := NewCoalescer(0)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{SysName: "sw"}}
+	auth := Auth{Version: 2, Community: "public"}
+
+	var wg sync.WaitGroup
+
+	results := make([]*DDMResult, 3)
+
+	for i := range results {
+		wg.Go(func() {
+			result, err := c.Getter("sw1", auth, client).GetDDMMetrics(t.Context())
+			assert.NoError(t, err)
+
+			results[i] = result
+		})
+	}
+
+	// wait for the other two scrapes to join the first one's walk
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(c.coalesced) == 2
+	}, time.Second, time.Millisecond)
+
+	close(client.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, client.count())
+
+	for _, r := range results {
+		assert.Same(t, client.result, r)
+	}
+
+	// without a TTL, the next scrape walks again
+	_, err := c.Getter("sw1", auth, client).GetDDMMetrics(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.count())
+}
+
+func TestCoalescer_TTL(t *testing.T) {
+	c := NewCoalescer(time.Hour)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{SysName: "sw"}}
+	close(client.release)
+
+	auth := Auth{Version: 2, Community: "public"}
+
+	for range 3 {
+		_, err := c.Getter("sw1", auth, client).GetDDMMetrics(t.Context())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, client.count())
+	assert.InDelta(t, 2, testutil.ToFloat64(c.coalesced), 0)
+
+	// other targets and other credentials aren't shared
+	_, err := c.Getter("sw2", auth, client).GetDDMMetrics(t.Context())
+	require.NoError(t, err)
+
+	_, err = c.Getter("sw1", Auth{Version: 2, Community: "private"}, client).GetDDMMetrics(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, client.count())
+}
+
+func TestCoalescer_WaiterCancelled(t *testing.T) {
+	c := NewCoalescer(0)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{SysName: "sw"}}
+	auth := Auth{Version: 2}
+
+	first := make(chan error, 1)
+
+	go func() {
+		_, err := c.Getter("sw1", auth, client).GetDDMMetrics(t.Context())
+		first <- err
+	}()
+
+	require.Eventually(t, func() bool { return client.count() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := c.Getter("sw1", auth, client).GetDDMMetrics(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// the shared walk carries on for the first scrape
+	close(client.release)
+	require.NoError(t, <-first)
+}
+
+func TestCoalescer_LongestDeadline(t *testing.T) {
+	c := NewCoalescer(0)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{SysName: "sw"}}
+	auth := Auth{Version: 2}
+
+	short, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	first := make(chan error, 1)
+
+	go func() {
+		_, err := c.Getter("sw1", auth, client).GetDDMMetrics(short)
+		first <- err
+	}()
+
+	require.Eventually(t, func() bool { return client.count() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan error, 1)
+
+	go func() {
+		_, err := c.Getter("sw1", auth, client).GetDDMMetrics(t.Context())
+		second <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(c.coalesced) == 1
+	}, time.Second, time.Millisecond)
+
+	// the scrape which started the walk times out, but the walk carries on
+	// for the scrape with more time left
+	require.ErrorIs(t, <-first, context.DeadlineExceeded)
+
+	close(client.release)
+	require.NoError(t, <-second)
+	assert.Equal(t, 1, client.count())
+}
+
+func TestSharedContext(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+
+	ctx, cancel := context.WithDeadline(t.Context(), deadline)
+
+	shared := newSharedContext(ctx)
+	defer shared.stop()
+
+	cancel()
+
+	// not cancelled with the scrape's context, but with the same deadline
+	require.NoError(t, shared.Err())
+
+	got, ok := shared.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, deadline, got)
+
+	// a joining scrape can only push the deadline back
+	earlier, cancel := context.WithDeadline(t.Context(), deadline.Add(-time.Minute))
+	defer cancel()
+
+	shared.extend(earlier)
+
+	got, _ = shared.Deadline()
+	assert.Equal(t, deadline, got)
+
+	later, cancel := context.WithDeadline(t.Context(), deadline.Add(time.Hour))
+	defer cancel()
+
+	shared.extend(later)
+
+	got, _ = shared.Deadline()
+	assert.Equal(t, deadline.Add(time.Hour), got)
+
+	// and one without a deadline removes it
+	shared.extend(t.Context())
+
+	_, ok = shared.Deadline()
+	assert.False(t, ok)
+
+	shared.stop()
+	require.ErrorIs(t, shared.Err(), context.Canceled)
+}
+
+func TestSharedContext_Expires(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+	defer cancel()
+
+	shared := newSharedContext(ctx)
+	defer shared.stop()
+
+	<-shared.Done()
+	assert.ErrorIs(t, shared.Err(), context.DeadlineExceeded)
+}
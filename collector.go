@@ -2,21 +2,65 @@ package tplinkddm
 
 import (
 	"context"
+	"errors"
 	"log/slog"
-
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Scrape error reasons, used as the reason label of tplink_ddm_scrape_error
+const (
+	reasonTimeout = "timeout"
+	reasonAuth    = "auth"
+	reasonConnect = "connect"
+	reasonNoData  = "no_data"
+	reasonParse   = "parse"
+)
+
+//nolint:gochecknoglobals // fixed label values
+var scrapeErrorReasons = []string{reasonTimeout, reasonAuth, reasonConnect, reasonNoData, reasonParse}
+
 // SNMPGetter defines the interface for getting DDM metrics via SNMP
 type SNMPGetter interface {
 	GetDDMMetrics(ctx context.Context) (*DDMResult, error)
 }
 
+// lastSuccessReporter is implemented by SNMPGetters which remember when they
+// last walked the switch successfully, like the Poller's cached results
+type lastSuccessReporter interface {
+	LastSuccess() time.Time
+}
+
 // Collector collects DDM metrics from TP-Link switch
 type Collector struct { //nolint:govet // field grouping by category is clearer than optimal alignment
-	snmpClient SNMPGetter
-	target     string
-	ctx        context.Context //nolint:containedctx // per-request collector, context set by HTTP handler
+	snmpClient    SNMPGetter
+	target        string
+	ctx           context.Context //nolint:containedctx // per-request collector, context set by HTTP handler
+	moduleTracker *ModuleTracker
+	parseCounts   *ParseErrorCounts
+	emptyCages    string
+	missingAsNaN  bool
+
+	// Scrape health
+	up             *prometheus.GaugeVec
+	scrapeDuration *prometheus.GaugeVec
+	scrapePDUs     *prometheus.GaugeVec
+	scrapeError    *prometheus.GaugeVec
+	lastSuccess    *prometheus.GaugeVec
+	parseErrors    *prometheus.CounterVec
+
+	// Switch identity and health
+	switchInfo *prometheus.GaugeVec
+	uptime     *prometheus.GaugeVec
+	cpuUtil    *prometheus.GaugeVec
+	memoryUtil *prometheus.GaugeVec
 
 	// Current values
 	temp     *prometheus.GaugeVec
@@ -24,6 +68,22 @@ type Collector struct { //nolint:govet // field grouping by category is clearer
 	biasCurr *prometheus.GaugeVec
 	txPower  *prometheus.GaugeVec
 	rxPower  *prometheus.GaugeVec
+	txWatts  *prometheus.GaugeVec
+	rxWatts  *prometheus.GaugeVec
+
+	// Port identity
+	portInfo   *prometheus.GaugeVec
+	sfpPresent *prometheus.GaugeVec
+
+	// Transceiver inventory
+	sfpInfo         *prometheus.GaugeVec
+	moduleChanges   *prometheus.CounterVec
+	moduleInstalled *prometheus.GaugeVec
+
+	// IF-MIB correlation
+	ifInfo     *prometheus.GaugeVec
+	operStatus *prometheus.GaugeVec
+	speed      *prometheus.GaugeVec
 
 	// Configuration
 	ddmEnabled     *prometheus.GaugeVec
@@ -41,18 +101,152 @@ type Collector struct { //nolint:govet // field grouping by category is clearer
 	biasCurrentThreshold *prometheus.GaugeVec
 	txPowerThreshold     *prometheus.GaugeVec
 	rxPowerThreshold     *prometheus.GaugeVec
+	txWattsThreshold     *prometheus.GaugeVec
+	rxWattsThreshold     *prometheus.GaugeVec
+
+	// Readings compared against thresholds, with labels: device, target, port, interface, measurement
+	thresholdState  *prometheus.GaugeVec
+	thresholdMargin *prometheus.GaugeVec
+}
+
+// CollectorOption configures a Collector
+type CollectorOption func(*Collector)
+
+// WithModuleTracker enables SFP module swap detection, using a tracker shared
+// between scrapes
+func WithModuleTracker(t *ModuleTracker) CollectorOption {
+	return func(c *Collector) {
+		c.moduleTracker = t
+	}
+}
+
+// How the collector exports ports with no transceiver inserted
+const (
+	// EmptyCagesFlag exports tplink_sfp_present = 0 and the port's identity,
+	// without any readings (the default)
+	EmptyCagesFlag = "flag"
+	// EmptyCagesSkip exports nothing for empty cages
+	EmptyCagesSkip = "skip"
+	// EmptyCagesAll exports all series for empty cages, as zeros or as
+	// missing readings
+	EmptyCagesAll = "all"
+)
+
+// ValidEmptyCages reports whether mode is one of the EmptyCages modes
+func ValidEmptyCages(mode string) bool {
+	return mode == EmptyCagesFlag || mode == EmptyCagesSkip || mode == EmptyCagesAll
+}
+
+// WithEmptyCages sets how ports with no transceiver are exported: one of
+// EmptyCagesFlag (the default), EmptyCagesSkip or EmptyCagesAll
+func WithEmptyCages(mode string) CollectorOption {
+	return func(c *Collector) {
+		if mode != "" {
+			c.emptyCages = mode
+		}
+	}
+}
+
+// WithParseErrorCounts accumulates tplink_ddm_parse_errors_total across
+// scrapes. Without it, the counter only covers the current scrape.
+func WithParseErrorCounts(p *ParseErrorCounts) CollectorOption {
+	return func(c *Collector) {
+		c.parseCounts = p
+	}
+}
+
+// WithMissingAsNaN emits readings which were missing or unparseable as NaN,
+// instead of omitting their series
+func WithMissingAsNaN(nan bool) CollectorOption {
+	return func(c *Collector) {
+		c.missingAsNaN = nan
+	}
 }
 
 // NewCollector creates a new DDM collector for a given target
 //
 //nolint:funlen,dupl // Multiple metric definitions required
-func NewCollector(snmpClient *SNMPClient, target string) *Collector {
-	labels := []string{"device", "target", "port"}
-	thresholdLabels := []string{"device", "target", "port", "level", "type"}
+func NewCollector(snmpClient SNMPGetter, target string, opts ...CollectorOption) *Collector {
+	labels := []string{"device", "target", "port", "interface"}
+	thresholdLabels := []string{"device", "target", "port", "interface", "level", "type"}
 
-	return &Collector{
+	c := &Collector{
 		snmpClient: snmpClient,
 		target:     target,
+		emptyCages: EmptyCagesFlag,
+		// Scrape health
+		up: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_ddm_up",
+				Help: "Whether the last scrape of the switch succeeded (1 = success, 0 = failure)",
+			},
+			[]string{"target"},
+		),
+		scrapeDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_ddm_scrape_duration_seconds",
+				Help: "Time taken to walk the switch",
+			},
+			[]string{"target"},
+		),
+		scrapePDUs: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				//nolint:promlinter // named to match snmp_exporter-style scrape metrics
+				Name: "tplink_ddm_scrape_pdus_total",
+				Help: "Number of SNMP PDUs returned by the walk",
+			},
+			[]string{"target"},
+		),
+		scrapeError: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_ddm_scrape_error",
+				Help: "Why the last scrape failed (1 for the failure reason, 0 otherwise)",
+			},
+			[]string{"target", "reason"},
+		),
+		lastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_ddm_last_successful_scrape_timestamp_seconds",
+				Help: "When the switch was last walked successfully, to tell stale results apart in polling mode",
+			},
+			[]string{"target"},
+		),
+		parseErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tplink_ddm_parse_errors_total",
+				Help: "Number of DDM readings the switch returned which weren't numbers, by field",
+			},
+			[]string{"target", "field"},
+		),
+		// Switch identity and health
+		switchInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_switch_info",
+				Help: "Switch model, firmware and hardware versions, always 1",
+			},
+			[]string{"device", "target", "model", "firmware", "hardware", "sys_object_id"},
+		),
+		uptime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_switch_uptime_seconds",
+				Help: "Time since the switch's SNMP agent started (sysUpTime)",
+			},
+			[]string{"device", "target"},
+		),
+		cpuUtil: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_switch_cpu_utilization_ratio",
+				Help: "CPU utilization of each stack unit over the last minute (0-1)",
+			},
+			[]string{"device", "target", "unit"},
+		),
+		memoryUtil: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_switch_memory_utilization_ratio",
+				Help: "Memory utilization of each stack unit (0-1)",
+			},
+			[]string{"device", "target", "unit"},
+		),
 		// Current values
 		temp: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -89,6 +283,82 @@ func NewCollector(snmpClient *SNMPClient, target string) *Collector {
 			},
 			labels,
 		),
+		txWatts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_tx_power_watts",
+				Help: "SFP TX power in watts",
+			},
+			labels,
+		),
+		rxWatts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_rx_power_watts",
+				Help: "SFP RX power in watts",
+			},
+			labels,
+		),
+		// Port identity
+		portInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_port_info",
+				Help: "Port identity, always 1. Unit and slot are empty for switches reporting plain port numbers.",
+			},
+			[]string{"device", "target", "port", "interface", "unit", "slot"},
+		),
+		sfpPresent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_present",
+				Help: "Whether a transceiver is inserted in the port (1 = present, 0 = empty cage)",
+			},
+			labels,
+		),
+		// Transceiver inventory
+		sfpInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_info",
+				Help: "SFP transceiver inventory, always 1",
+			},
+			[]string{
+				"device", "target", "port", "interface",
+				"vendor", "part_number", "serial", "revision", "wavelength_nm", "connector", "media_type",
+			},
+		),
+		moduleChanges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "tplink_sfp_module_changes_total",
+				Help: "Number of times a different SFP module was seen in the port",
+			},
+			labels,
+		),
+		moduleInstalled: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_module_installed_timestamp_seconds",
+				Help: "When the current SFP module was first seen in the port, as a Unix timestamp",
+			},
+			labels,
+		),
+		// IF-MIB correlation
+		ifInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_port_info",
+				Help: "IF-MIB interface matching the port, always 1",
+			},
+			[]string{"device", "target", "port", "interface", "ifIndex", "ifName", "ifAlias"},
+		),
+		operStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_port_oper_status",
+				Help: "IF-MIB ifOperStatus of the port (1 = up, 2 = down, 3 = testing, 4 = unknown, 5 = dormant, 6 = notPresent, 7 = lowerLayerDown)",
+			},
+			labels,
+		),
+		speed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_port_speed_bits_per_second",
+				Help: "IF-MIB ifHighSpeed of the port, in bits per second",
+			},
+			labels,
+		),
 		// Configuration
 		ddmEnabled: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
@@ -109,7 +379,7 @@ func NewCollector(snmpClient *SNMPClient, target string) *Collector {
 				Name: "tplink_port_lag_member",
 				Help: "Port LAG/trunk membership (1 = member, 0 = not member). LAG name in label.",
 			},
-			[]string{"device", "target", "port", "lag"},
+			[]string{"device", "target", "port", "interface", "lag"},
 		),
 		// Status flags
 		ddmSupported: prometheus.NewGaugeVec(
@@ -169,16 +439,70 @@ func NewCollector(snmpClient *SNMPClient, target string) *Collector {
 			},
 			thresholdLabels,
 		),
+		txWattsThreshold: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_tx_power_threshold_watts",
+				Help: "SFP TX power threshold in watts",
+			},
+			thresholdLabels,
+		),
+		rxWattsThreshold: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_rx_power_threshold_watts",
+				Help: "SFP RX power threshold in watts",
+			},
+			thresholdLabels,
+		),
+		thresholdState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_threshold_state",
+				Help: "Whether the reading is in the given threshold state (1) or not (0), by measurement",
+			},
+			[]string{"device", "target", "port", "interface", "measurement", "state"},
+		),
+		thresholdMargin: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tplink_sfp_threshold_margin",
+				Help: "Signed distance from the reading to the nearest threshold, in the measurement's unit (negative once past it)",
+			},
+			[]string{"device", "target", "port", "interface", "measurement"},
+		),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 // Describe implements prometheus.Collector
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.up.Describe(ch)
+	c.scrapeDuration.Describe(ch)
+	c.scrapePDUs.Describe(ch)
+	c.scrapeError.Describe(ch)
+	c.lastSuccess.Describe(ch)
+	c.parseErrors.Describe(ch)
+	c.switchInfo.Describe(ch)
+	c.uptime.Describe(ch)
+	c.cpuUtil.Describe(ch)
+	c.memoryUtil.Describe(ch)
 	c.temp.Describe(ch)
 	c.voltage.Describe(ch)
 	c.biasCurr.Describe(ch)
 	c.txPower.Describe(ch)
 	c.rxPower.Describe(ch)
+	c.txWatts.Describe(ch)
+	c.rxWatts.Describe(ch)
+	c.portInfo.Describe(ch)
+	c.sfpPresent.Describe(ch)
+	c.sfpInfo.Describe(ch)
+	c.moduleChanges.Describe(ch)
+	c.moduleInstalled.Describe(ch)
+	c.ifInfo.Describe(ch)
+	c.operStatus.Describe(ch)
+	c.speed.Describe(ch)
 	c.ddmEnabled.Describe(ch)
 	c.shutdownPolicy.Describe(ch)
 	c.portLAG.Describe(ch)
@@ -190,6 +514,10 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	c.biasCurrentThreshold.Describe(ch)
 	c.txPowerThreshold.Describe(ch)
 	c.rxPowerThreshold.Describe(ch)
+	c.txWattsThreshold.Describe(ch)
+	c.rxWattsThreshold.Describe(ch)
+	c.thresholdState.Describe(ch)
+	c.thresholdMargin.Describe(ch)
 }
 
 // WithContext returns the collector with the given context set, for trace propagation.
@@ -213,21 +541,42 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 //
 //nolint:funlen // Multiple metrics to collect and export
 func (c *Collector) CollectWithContext(ctx context.Context, ch chan<- prometheus.Metric) {
+	start := time.Now()
 	result, err := c.snmpClient.GetDDMMetrics(ctx)
+	duration := time.Since(start)
+
+	c.collectHealth(ch, result, err, duration)
+
 	if err != nil {
-		slog.Error("failed to scrape metrics", "error", err)
+		slog.ErrorContext(ctx, "failed to scrape metrics", "target", c.target, "error", err)
 
 		return
 	}
 
 	device := result.SysName
+	now := time.Now()
 
 	// Reset all metrics
+	c.parseErrors.Reset()
+	c.switchInfo.Reset()
+	c.uptime.Reset()
+	c.cpuUtil.Reset()
+	c.memoryUtil.Reset()
 	c.temp.Reset()
 	c.voltage.Reset()
 	c.biasCurr.Reset()
 	c.txPower.Reset()
 	c.rxPower.Reset()
+	c.txWatts.Reset()
+	c.rxWatts.Reset()
+	c.portInfo.Reset()
+	c.sfpPresent.Reset()
+	c.sfpInfo.Reset()
+	c.moduleChanges.Reset()
+	c.moduleInstalled.Reset()
+	c.ifInfo.Reset()
+	c.operStatus.Reset()
+	c.speed.Reset()
 	c.ddmEnabled.Reset()
 	c.shutdownPolicy.Reset()
 	c.portLAG.Reset()
@@ -239,82 +588,160 @@ func (c *Collector) CollectWithContext(ctx context.Context, ch chan<- prometheus
 	c.biasCurrentThreshold.Reset()
 	c.txPowerThreshold.Reset()
 	c.rxPowerThreshold.Reset()
+	c.txWattsThreshold.Reset()
+	c.rxWattsThreshold.Reset()
+	c.thresholdState.Reset()
+	c.thresholdMargin.Reset()
+
+	c.setSystem(device, result.System)
+
+	seen := make(map[string]bool, len(result.Metrics))
+	fieldErrors := map[string]int{}
 
 	for _, m := range result.Metrics {
+		// the same interface twice would make WithLabelValues silently
+		// overwrite the first port's values with the second's
+		key := m.Port + "\x00" + m.Interface
+		if seen[key] {
+			slog.WarnContext(ctx, "skipping duplicate port", "target", c.target, "port", m.Port, "interface", m.Interface)
+
+			continue
+		}
+
+		seen[key] = true
+
+		if m.Empty && c.emptyCages == EmptyCagesSkip {
+			continue
+		}
+
+		c.sfpPresent.WithLabelValues(device, c.target, m.Port, m.Interface).Set(boolToFloat(!m.Empty))
+		c.portInfo.WithLabelValues(device, c.target, m.Port, m.Interface, m.Unit, m.Slot).Set(1)
+
+		if m.IfIndex != "" {
+			c.ifInfo.WithLabelValues(device, c.target, m.Port, m.Interface, m.IfIndex, m.IfName, m.IfAlias).Set(1)
+			c.operStatus.WithLabelValues(device, c.target, m.Port, m.Interface).Set(float64(m.OperStatus))
+			c.speed.WithLabelValues(device, c.target, m.Port, m.Interface).Set(m.Speed)
+		}
+
+		// an empty cage's readings are all zeros or placeholders
+		if m.Empty && c.emptyCages != EmptyCagesAll {
+			continue
+		}
+
+		if m.Vendor != "" || m.PartNumber != "" || m.Serial != "" {
+			wavelength := ""
+			if m.Wavelength > 0 {
+				wavelength = strconv.FormatFloat(m.Wavelength, 'f', -1, 64)
+			}
+
+			c.sfpInfo.WithLabelValues(device, c.target, m.Port, m.Interface,
+				m.Vendor, m.PartNumber, m.Serial, m.Revision, wavelength, m.Connector, m.MediaType).Set(1)
+		}
+
+		if c.moduleTracker != nil {
+			if rec, ok := c.moduleTracker.Observe(c.target, m, now); ok {
+				c.moduleChanges.WithLabelValues(device, c.target, m.Port, m.Interface).Add(float64(rec.Changes))
+				c.moduleInstalled.WithLabelValues(device, c.target, m.Port, m.Interface).Set(float64(rec.Installed.Unix()))
+			}
+		}
+
 		// Current values
-		c.temp.WithLabelValues(device, c.target, m.Port).Set(m.Temperature)
-		c.voltage.WithLabelValues(device, c.target, m.Port).Set(m.Voltage)
-		c.biasCurr.WithLabelValues(device, c.target, m.Port).Set(m.BiasCurrent / 1000)
-		c.txPower.WithLabelValues(device, c.target, m.Port).Set(m.TxPower)
-		c.rxPower.WithLabelValues(device, c.target, m.Port).Set(m.RxPower)
+		c.setReading(c.temp, &m, FieldTemperature, m.Temperature, device, c.target, m.Port, m.Interface)
+		c.setReading(c.voltage, &m, FieldVoltage, m.Voltage, device, c.target, m.Port, m.Interface)
+		c.setReading(c.biasCurr, &m, FieldBiasCurrent, m.BiasCurrent/1000, device, c.target, m.Port, m.Interface)
+		c.setReading(c.txPower, &m, FieldTxPower, m.TxPower, device, c.target, m.Port, m.Interface)
+		c.setReading(c.rxPower, &m, FieldRxPower, m.RxPower, device, c.target, m.Port, m.Interface)
+		c.setReading(c.txWatts, &m, FieldTxPower, dBmToWatts(m.TxPower), device, c.target, m.Port, m.Interface)
+		c.setReading(c.rxWatts, &m, FieldRxPower, dBmToWatts(m.RxPower), device, c.target, m.Port, m.Interface)
 
 		// Configuration
 		if m.DDMEnabled {
-			c.ddmEnabled.WithLabelValues(device, c.target, m.Port).Set(1)
+			c.ddmEnabled.WithLabelValues(device, c.target, m.Port, m.Interface).Set(1)
 		} else {
-			c.ddmEnabled.WithLabelValues(device, c.target, m.Port).Set(0)
+			c.ddmEnabled.WithLabelValues(device, c.target, m.Port, m.Interface).Set(0)
 		}
 
-		c.shutdownPolicy.WithLabelValues(device, c.target, m.Port).Set(float64(m.ShutdownPolicy))
+		c.shutdownPolicy.WithLabelValues(device, c.target, m.Port, m.Interface).Set(float64(m.ShutdownPolicy))
 
 		if m.LAGMembership != "" && m.LAGMembership != "N/A" && m.LAGMembership != "---" {
-			c.portLAG.WithLabelValues(device, c.target, m.Port, m.LAGMembership).Set(1)
+			c.portLAG.WithLabelValues(device, c.target, m.Port, m.Interface, m.LAGMembership).Set(1)
 		} else {
-			c.portLAG.WithLabelValues(device, c.target, m.Port, "").Set(0)
+			c.portLAG.WithLabelValues(device, c.target, m.Port, m.Interface, "").Set(0)
 		}
 
 		// Status flags
 		if m.DDMSupported {
-			c.ddmSupported.WithLabelValues(device, c.target, m.Port).Set(1)
+			c.ddmSupported.WithLabelValues(device, c.target, m.Port, m.Interface).Set(1)
 		} else {
-			c.ddmSupported.WithLabelValues(device, c.target, m.Port).Set(0)
+			c.ddmSupported.WithLabelValues(device, c.target, m.Port, m.Interface).Set(0)
 		}
 
 		if m.LossOfSignal {
-			c.lossOfSignal.WithLabelValues(device, c.target, m.Port).Set(1)
+			c.lossOfSignal.WithLabelValues(device, c.target, m.Port, m.Interface).Set(1)
 		} else {
-			c.lossOfSignal.WithLabelValues(device, c.target, m.Port).Set(0)
+			c.lossOfSignal.WithLabelValues(device, c.target, m.Port, m.Interface).Set(0)
 		}
 
 		if m.TxFault {
-			c.txFault.WithLabelValues(device, c.target, m.Port).Set(1)
+			c.txFault.WithLabelValues(device, c.target, m.Port, m.Interface).Set(1)
 		} else {
-			c.txFault.WithLabelValues(device, c.target, m.Port).Set(0)
+			c.txFault.WithLabelValues(device, c.target, m.Port, m.Interface).Set(0)
 		}
 
 		// Thresholds
-		c.tempThreshold.WithLabelValues(device, c.target, m.Port, "high", "alarm").Set(m.TemperatureHighAlarm)
-		c.tempThreshold.WithLabelValues(device, c.target, m.Port, "low", "alarm").Set(m.TemperatureLowAlarm)
-		c.tempThreshold.WithLabelValues(device, c.target, m.Port, "high", "warning").Set(m.TemperatureHighWarning)
-		c.tempThreshold.WithLabelValues(device, c.target, m.Port, "low", "warning").Set(m.TemperatureLowWarning)
-
-		c.voltageThreshold.WithLabelValues(device, c.target, m.Port, "high", "alarm").Set(m.VoltageHighAlarm)
-		c.voltageThreshold.WithLabelValues(device, c.target, m.Port, "low", "alarm").Set(m.VoltageLowAlarm)
-		c.voltageThreshold.WithLabelValues(device, c.target, m.Port, "high", "warning").Set(m.VoltageHighWarning)
-		c.voltageThreshold.WithLabelValues(device, c.target, m.Port, "low", "warning").Set(m.VoltageLowWarning)
-
-		c.biasCurrentThreshold.WithLabelValues(device, c.target, m.Port, "high", "alarm").Set(m.BiasCurrentHighAlarm / 1000)
-		c.biasCurrentThreshold.WithLabelValues(device, c.target, m.Port, "low", "alarm").Set(m.BiasCurrentLowAlarm / 1000)
-		c.biasCurrentThreshold.WithLabelValues(device, c.target, m.Port, "high", "warning").Set(m.BiasCurrentHighWarning / 1000)
-		c.biasCurrentThreshold.WithLabelValues(device, c.target, m.Port, "low", "warning").Set(m.BiasCurrentLowWarning / 1000)
-
-		c.txPowerThreshold.WithLabelValues(device, c.target, m.Port, "high", "alarm").Set(m.TxPowerHighAlarm)
-		c.txPowerThreshold.WithLabelValues(device, c.target, m.Port, "low", "alarm").Set(m.TxPowerLowAlarm)
-		c.txPowerThreshold.WithLabelValues(device, c.target, m.Port, "high", "warning").Set(m.TxPowerHighWarning)
-		c.txPowerThreshold.WithLabelValues(device, c.target, m.Port, "low", "warning").Set(m.TxPowerLowWarning)
-
-		c.rxPowerThreshold.WithLabelValues(device, c.target, m.Port, "high", "alarm").Set(m.RxPowerHighAlarm)
-		c.rxPowerThreshold.WithLabelValues(device, c.target, m.Port, "low", "alarm").Set(m.RxPowerLowAlarm)
-		c.rxPowerThreshold.WithLabelValues(device, c.target, m.Port, "high", "warning").Set(m.RxPowerHighWarning)
-		c.rxPowerThreshold.WithLabelValues(device, c.target, m.Port, "low", "warning").Set(m.RxPowerLowWarning)
+		lvs := []string{device, c.target, m.Port, m.Interface}
+		c.setThresholds(c.tempThreshold, &m, FieldTemperature, lvs,
+			m.TemperatureHighAlarm, m.TemperatureLowAlarm, m.TemperatureHighWarning, m.TemperatureLowWarning)
+		c.setThresholds(c.voltageThreshold, &m, FieldVoltage, lvs,
+			m.VoltageHighAlarm, m.VoltageLowAlarm, m.VoltageHighWarning, m.VoltageLowWarning)
+		c.setThresholds(c.biasCurrentThreshold, &m, FieldBiasCurrent, lvs,
+			m.BiasCurrentHighAlarm/1000, m.BiasCurrentLowAlarm/1000, m.BiasCurrentHighWarning/1000, m.BiasCurrentLowWarning/1000)
+		c.setThresholds(c.txPowerThreshold, &m, FieldTxPower, lvs,
+			m.TxPowerHighAlarm, m.TxPowerLowAlarm, m.TxPowerHighWarning, m.TxPowerLowWarning)
+		c.setThresholds(c.rxPowerThreshold, &m, FieldRxPower, lvs,
+			m.RxPowerHighAlarm, m.RxPowerLowAlarm, m.RxPowerHighWarning, m.RxPowerLowWarning)
+		c.setThresholds(c.txWattsThreshold, &m, FieldTxPower, lvs,
+			dBmToWatts(m.TxPowerHighAlarm), dBmToWatts(m.TxPowerLowAlarm),
+			dBmToWatts(m.TxPowerHighWarning), dBmToWatts(m.TxPowerLowWarning))
+		c.setThresholds(c.rxWattsThreshold, &m, FieldRxPower, lvs,
+			dBmToWatts(m.RxPowerHighAlarm), dBmToWatts(m.RxPowerLowAlarm),
+			dBmToWatts(m.RxPowerHighWarning), dBmToWatts(m.RxPowerLowWarning))
+		c.setThresholdStates(&m, lvs)
+
+		for _, field := range m.ParseErrors {
+			fieldErrors[field]++
+		}
+	}
+
+	if c.parseCounts != nil {
+		fieldErrors = c.parseCounts.add(c.target, result.Time, fieldErrors)
+	}
+
+	for field, n := range fieldErrors {
+		c.parseErrors.WithLabelValues(c.target, field).Add(float64(n))
 	}
 
 	// Collect all metrics
+	c.parseErrors.Collect(ch)
+	c.switchInfo.Collect(ch)
+	c.uptime.Collect(ch)
+	c.cpuUtil.Collect(ch)
+	c.memoryUtil.Collect(ch)
 	c.temp.Collect(ch)
 	c.voltage.Collect(ch)
 	c.biasCurr.Collect(ch)
 	c.txPower.Collect(ch)
 	c.rxPower.Collect(ch)
+	c.txWatts.Collect(ch)
+	c.rxWatts.Collect(ch)
+	c.portInfo.Collect(ch)
+	c.sfpPresent.Collect(ch)
+	c.sfpInfo.Collect(ch)
+	c.moduleChanges.Collect(ch)
+	c.moduleInstalled.Collect(ch)
+	c.ifInfo.Collect(ch)
+	c.operStatus.Collect(ch)
+	c.speed.Collect(ch)
 	c.ddmEnabled.Collect(ch)
 	c.shutdownPolicy.Collect(ch)
 	c.portLAG.Collect(ch)
@@ -326,4 +753,162 @@ func (c *Collector) CollectWithContext(ctx context.Context, ch chan<- prometheus
 	c.biasCurrentThreshold.Collect(ch)
 	c.txPowerThreshold.Collect(ch)
 	c.rxPowerThreshold.Collect(ch)
+	c.txWattsThreshold.Collect(ch)
+	c.rxWattsThreshold.Collect(ch)
+	c.thresholdState.Collect(ch)
+	c.thresholdMargin.Collect(ch)
+}
+
+// setReading sets a DDM reading's gauge. Readings the switch didn't report
+// (or reported as garbage) are omitted, or set to NaN if configured.
+func (c *Collector) setReading(g *prometheus.GaugeVec, m *DDMMetrics, field string, v float64, lvs ...string) {
+	switch {
+	case m.Valid(field):
+		g.WithLabelValues(lvs...).Set(v)
+	case c.missingAsNaN:
+		g.WithLabelValues(lvs...).Set(math.NaN())
+	}
+}
+
+// setThresholdStates sets the threshold state and margin of each measurement
+// the module has thresholds for
+func (c *Collector) setThresholdStates(m *DDMMetrics, lvs []string) {
+	for _, measurement := range thresholdMeasurements {
+		state, margin, ok := m.ThresholdState(measurement)
+		if !ok {
+			continue
+		}
+
+		// bias current is exported in amperes, but reported in mA
+		if measurement == FieldBiasCurrent {
+			margin /= 1000
+		}
+
+		mlvs := append(slices.Clone(lvs), measurement)
+		c.thresholdMargin.WithLabelValues(mlvs...).Set(margin)
+
+		for _, s := range thresholdStates {
+			c.thresholdState.WithLabelValues(append(slices.Clone(mlvs), s)...).Set(boolToFloat(s == state))
+		}
+	}
+}
+
+// setThresholds sets the four alarm and warning thresholds for a measurement
+func (c *Collector) setThresholds(g *prometheus.GaugeVec, m *DDMMetrics, measurement string, lvs []string,
+	highAlarm, lowAlarm, highWarning, lowWarning float64,
+) {
+	for _, t := range []struct {
+		level, typ string
+		v          float64
+	}{
+		{"high", "alarm", highAlarm},
+		{"low", "alarm", lowAlarm},
+		{"high", "warning", highWarning},
+		{"low", "warning", lowWarning},
+	} {
+		c.setReading(g, m, thresholdField(measurement, t.level, t.typ), t.v, append(slices.Clone(lvs), t.level, t.typ)...)
+	}
+}
+
+// setSystem sets the switch identity and health metrics
+func (c *Collector) setSystem(device string, sys SystemInfo) {
+	c.switchInfo.WithLabelValues(device, c.target, sys.Model, sys.Firmware, sys.Hardware, sys.ObjectID).Set(1)
+
+	if sys.Uptime > 0 {
+		c.uptime.WithLabelValues(device, c.target).Set(sys.Uptime.Seconds())
+	}
+
+	for unit, v := range sys.CPU {
+		c.cpuUtil.WithLabelValues(device, c.target, unit).Set(v)
+	}
+
+	for unit, v := range sys.Memory {
+		c.memoryUtil.WithLabelValues(device, c.target, unit).Set(v)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// collectHealth emits the up, duration, PDU count and error reason metrics,
+// which are present whether or not the scrape succeeded, and the time of the
+// last successful walk when it's known.
+func (c *Collector) collectHealth(ch chan<- prometheus.Metric, result *DDMResult, err error, duration time.Duration) {
+	c.up.Reset()
+	c.scrapeDuration.Reset()
+	c.scrapePDUs.Reset()
+	c.scrapeError.Reset()
+	c.lastSuccess.Reset()
+
+	c.scrapeDuration.WithLabelValues(c.target).Set(duration.Seconds())
+
+	reason := ""
+	if err != nil {
+		reason = scrapeErrorReason(err)
+		c.up.WithLabelValues(c.target).Set(0)
+		c.scrapePDUs.WithLabelValues(c.target).Set(0)
+	} else {
+		c.up.WithLabelValues(c.target).Set(1)
+		c.scrapePDUs.WithLabelValues(c.target).Set(float64(result.PDUs))
+	}
+
+	for _, r := range scrapeErrorReasons {
+		v := 0.0
+		if r == reason {
+			v = 1
+		}
+
+		c.scrapeError.WithLabelValues(c.target, r).Set(v)
+	}
+
+	var last time.Time
+	if err == nil {
+		last = result.Time
+	} else if r, ok := c.snmpClient.(lastSuccessReporter); ok {
+		last = r.LastSuccess()
+	}
+
+	if !last.IsZero() {
+		c.lastSuccess.WithLabelValues(c.target).Set(float64(last.UnixNano()) / 1e9)
+	}
+
+	c.up.Collect(ch)
+	c.scrapeDuration.Collect(ch)
+	c.scrapePDUs.Collect(ch)
+	c.scrapeError.Collect(ch)
+	c.lastSuccess.Collect(ch)
+}
+
+// scrapeErrorReason classifies a scrape error for the reason label of
+// tplink_ddm_scrape_error.
+func scrapeErrorReason(err error) string {
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, ErrNoData):
+		return reasonNoData
+	case errors.Is(err, ErrParse):
+		return reasonParse
+	case errors.Is(err, ErrInvalidAuth),
+		errors.Is(err, gosnmp.ErrUnknownUsername),
+		errors.Is(err, gosnmp.ErrWrongDigest),
+		errors.Is(err, gosnmp.ErrUnknownSecurityLevel),
+		errors.Is(err, gosnmp.ErrDecryption),
+		strings.Contains(err.Error(), "not authentic"):
+		return reasonAuth
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(),
+		strings.Contains(err.Error(), "timeout"):
+		// gosnmp reports its own retries running out as a plain
+		// "request timeout" error
+		return reasonTimeout
+	default:
+		// anything else is a transport failure: resolution, refused, etc.
+		return reasonConnect
+	}
 }
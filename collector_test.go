@@ -2,11 +2,18 @@ package tplinkddm
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gosnmp/gosnmp"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // mockSNMPClient implements a mock SNMP client for testing
@@ -51,8 +58,9 @@ func TestCollector_Collect(t *testing.T) {
 				},
 			},
 			target: "192.168.1.1",
-			// 5 current + 3 config + 3 status + 5*4 thresholds = 31 per port, * 2 ports = 62
-			wantCount: 62,
+			// 2 info + 7 current + 3 config + 3 status + 7*4 thresholds = 43 per port, * 2 ports = 86,
+			// plus 1 switch info + 3 health + 5 error reasons = 95
+			wantCount: 95,
 		},
 		{
 			name: "empty sysName",
@@ -70,13 +78,13 @@ func TestCollector_Collect(t *testing.T) {
 				},
 			},
 			target:    "192.168.1.2",
-			wantCount: 31, // 31 metrics * 1 port
+			wantCount: 52, // 43 metrics * 1 port + 1 switch info + 8 health
 		},
 		{
 			name:      "SNMP error",
 			mockErr:   context.DeadlineExceeded,
 			target:    "192.168.1.3",
-			wantCount: 0,
+			wantCount: 8, // health metrics only
 		},
 	}
 
@@ -98,10 +106,435 @@ func TestCollector_Collect(t *testing.T) {
 	}
 }
 
+func TestCollector_Health(t *testing.T) {
+	tests := []struct {
+		err        error
+		result     *DDMResult
+		name       string
+		wantReason string
+		wantUp     float64
+	}{
+		{
+			name:   "success",
+			result: &DDMResult{SysName: "sw", PDUs: 42, Metrics: []DDMMetrics{{Port: "1"}}},
+			wantUp: 1,
+		},
+		{name: "timeout", err: context.DeadlineExceeded, wantReason: reasonTimeout},
+		{name: "gosnmp timeout", err: fmt.Errorf("DDM root walk failed: %w", errors.New("request timeout (after 1 retries)")), wantReason: reasonTimeout},
+		{name: "auth", err: fmt.Errorf("walk: %w", gosnmp.ErrUnknownUsername), wantReason: reasonAuth},
+		{name: "invalid auth", err: fmt.Errorf("%w: bad", ErrInvalidAuth), wantReason: reasonAuth},
+		{name: "connect", err: fmt.Errorf("%w: no such host", ErrConnect), wantReason: reasonConnect},
+		{name: "no data", err: fmt.Errorf("%w found", ErrNoData), wantReason: reasonNoData},
+		{name: "parse", err: fmt.Errorf("%w: 3 ports", ErrParse), wantReason: reasonParse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := newTestCollector(&mockSNMPClient{result: tt.result, err: tt.err}, "10.0.0.1")
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(collector)
+
+			families, err := registry.Gather()
+			require.NoError(t, err)
+
+			got := map[string]float64{}
+
+			for _, mf := range families {
+				for _, m := range mf.GetMetric() {
+					key := mf.GetName()
+
+					for _, l := range m.GetLabel() {
+						if l.GetName() == "reason" {
+							key += "/" + l.GetValue()
+						}
+					}
+
+					got[key] = m.GetGauge().GetValue()
+				}
+			}
+
+			assert.InDelta(t, tt.wantUp, got["test_up"], 0)
+			assert.Contains(t, got, "test_scrape_duration")
+
+			for _, reason := range scrapeErrorReasons {
+				want := 0.0
+				if reason == tt.wantReason {
+					want = 1
+				}
+
+				assert.InDelta(t, want, got["test_scrape_error/"+reason], 0, reason)
+			}
+
+			if tt.result != nil {
+				assert.InDelta(t, float64(tt.result.PDUs), got["test_scrape_pdus"], 0)
+			}
+		})
+	}
+}
+
+func TestCollector_StackedPorts(t *testing.T) {
+	result := &DDMResult{
+		SysName: "stack",
+		Metrics: []DDMMetrics{
+			{Port: "5", Interface: "1/0/5", Unit: "1", Slot: "0", Temperature: 40},
+			{Port: "5", Interface: "2/0/5", Unit: "2", Slot: "0", Temperature: 50},
+			// duplicate of the first; must not overwrite its values
+			{Port: "5", Interface: "1/0/5", Unit: "1", Slot: "0", Temperature: 99},
+		},
+	}
+
+	collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_temp h
+# TYPE test_temp gauge
+test_temp{device="stack",interface="1/0/5",port="5",target="10.0.0.1"} 40
+test_temp{device="stack",interface="2/0/5",port="5",target="10.0.0.1"} 50
+# HELP test_port_info h
+# TYPE test_port_info gauge
+test_port_info{device="stack",interface="1/0/5",port="5",slot="0",target="10.0.0.1",unit="1"} 1
+test_port_info{device="stack",interface="2/0/5",port="5",slot="0",target="10.0.0.1",unit="2"} 1
+`), "test_temp", "test_port_info")
+	assert.NoError(t, err)
+}
+
+func TestCollector_IfMIB(t *testing.T) {
+	result := &DDMResult{
+		SysName: "sw",
+		Metrics: []DDMMetrics{
+			{
+				Port: "25", Interface: "1/0/25",
+				IfIndex: "49177", IfName: "gigabitEthernet 1/0/25", IfAlias: "uplink to core-2",
+				OperStatus: 1, Speed: 10e9,
+			},
+			// no matching interface: no IF-MIB series
+			{Port: "26", Interface: "1/0/26"},
+		},
+	}
+
+	collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_if_info h
+# TYPE test_if_info gauge
+test_if_info{device="sw",ifAlias="uplink to core-2",ifIndex="49177",ifName="gigabitEthernet 1/0/25",interface="1/0/25",port="25",target="10.0.0.1"} 1
+# HELP test_oper_status h
+# TYPE test_oper_status gauge
+test_oper_status{device="sw",interface="1/0/25",port="25",target="10.0.0.1"} 1
+# HELP test_speed h
+# TYPE test_speed gauge
+test_speed{device="sw",interface="1/0/25",port="25",target="10.0.0.1"} 1e+10
+`), "test_if_info", "test_oper_status", "test_speed")
+	assert.NoError(t, err)
+}
+
+func TestCollector_SFPInfo(t *testing.T) {
+	result := &DDMResult{
+		SysName: "sw",
+		Metrics: []DDMMetrics{
+			{
+				Port: "25", Interface: "1/0/25",
+				Vendor: "TP-LINK", PartNumber: "TL-SM321B", Serial: "2190418001234", Revision: "1.0",
+				Wavelength: 1310, Connector: "LC", MediaType: "1000BASE-BX",
+			},
+			// empty cage: no inventory series
+			{Port: "26", Interface: "1/0/26"},
+		},
+	}
+
+	collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_sfp_info h
+# TYPE test_sfp_info gauge
+test_sfp_info{connector="LC",device="sw",interface="1/0/25",media_type="1000BASE-BX",part_number="TL-SM321B",port="25",revision="1.0",serial="2190418001234",target="10.0.0.1",vendor="TP-LINK",wavelength_nm="1310"} 1
+`), "test_sfp_info")
+	assert.NoError(t, err)
+}
+
+func TestCollector_ModuleTracker(t *testing.T) {
+	tracker, err := NewModuleTracker("")
+	require.NoError(t, err)
+
+	mock := &mockSNMPClient{result: &DDMResult{
+		SysName: "sw",
+		Metrics: []DDMMetrics{
+			{Port: "25", Interface: "1/0/25", Vendor: "FS", PartNumber: "SFP-10GLR-31", Serial: "A1"},
+			{Port: "26", Interface: "1/0/26"},
+		},
+	}}
+
+	collector := newTestCollector(mock, "10.0.0.1")
+	WithModuleTracker(tracker)(collector)
+
+	// the first scrape records the module, without counting a change
+	families := gatherValues(t, collector)
+	assert.InDelta(t, 0, families["test_module_changes_total"], 0)
+	assert.Positive(t, families["test_module_installed"])
+
+	// a different serial in the same port is a swap
+	mock.result.Metrics[0].Serial = "B2"
+
+	families = gatherValues(t, collector)
+	assert.InDelta(t, 1, families["test_module_changes_total"], 0)
+
+	// only ports with a module get series
+	assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_module_changes_total"))
+}
+
+// gatherValues collects c and returns the last value of each metric family
+func gatherValues(t *testing.T, c prometheus.Collector) map[string]float64 {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := map[string]float64{}
+
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			switch {
+			case m.GetCounter() != nil:
+				values[mf.GetName()] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				values[mf.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	return values
+}
+
+func TestCollector_System(t *testing.T) {
+	result := &DDMResult{
+		SysName: "sw",
+		System: SystemInfo{
+			Model: "T2600G-28SQ", Firmware: "3.0.0 Build 20230512 Rel.54136", Hardware: "T2600G-28SQ 3.0",
+			ObjectID: "1.3.6.1.4.1.11863.5.100", Uptime: 90 * time.Second,
+			CPU:    map[string]float64{"1": 0.09},
+			Memory: map[string]float64{"1": 0.35},
+		},
+		Metrics: []DDMMetrics{{Port: "1", Interface: "1/0/1"}},
+	}
+
+	collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_switch_info h
+# TYPE test_switch_info gauge
+test_switch_info{device="sw",firmware="3.0.0 Build 20230512 Rel.54136",hardware="T2600G-28SQ 3.0",model="T2600G-28SQ",sys_object_id="1.3.6.1.4.1.11863.5.100",target="10.0.0.1"} 1
+# HELP test_uptime h
+# TYPE test_uptime gauge
+test_uptime{device="sw",target="10.0.0.1"} 90
+# HELP test_cpu_util h
+# TYPE test_cpu_util gauge
+test_cpu_util{device="sw",target="10.0.0.1",unit="1"} 0.09
+# HELP test_memory_util h
+# TYPE test_memory_util gauge
+test_memory_util{device="sw",target="10.0.0.1",unit="1"} 0.35
+`), "test_switch_info", "test_uptime", "test_cpu_util", "test_memory_util")
+	assert.NoError(t, err)
+}
+
+func TestCollector_MissingReadings(t *testing.T) {
+	m := DDMMetrics{Port: "1", Interface: "1/0/1", Temperature: 40, RxPower: 0, TxPower: 0}
+	m.setInvalid(FieldRxPower)
+	m.setInvalid(FieldTxPower)
+	m.setInvalid(thresholdField(FieldTxPower, "high", "alarm"))
+	m.ParseErrors = []string{FieldTxPower}
+
+	result := &DDMResult{SysName: "sw", Metrics: []DDMMetrics{m}}
+
+	t.Run("omit", func(t *testing.T) {
+		collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_temp"))
+		assert.Equal(t, 0, testutil.CollectAndCount(collector, "test_rx"))
+		assert.Equal(t, 0, testutil.CollectAndCount(collector, "test_tx"))
+		assert.Equal(t, 3, testutil.CollectAndCount(collector, "test_tx_thresh"))
+		assert.Equal(t, 4, testutil.CollectAndCount(collector, "test_rx_thresh"))
+	})
+
+	t.Run("NaN", func(t *testing.T) {
+		collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+		WithMissingAsNaN(true)(collector)
+
+		values := gatherValues(t, collector)
+		assert.InDelta(t, 40, values["test_temp"], 0)
+		assert.True(t, math.IsNaN(values["test_rx"]))
+		assert.True(t, math.IsNaN(values["test_tx"]))
+	})
+
+	t.Run("parse errors accumulate across scrapes", func(t *testing.T) {
+		counts := NewParseErrorCounts()
+
+		for i := 1; i <= 3; i++ {
+			collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+			WithParseErrorCounts(counts)(collector)
+
+			err := testutil.CollectAndCompare(collector, strings.NewReader(fmt.Sprintf(`
+# HELP test_parse_errors_total h
+# TYPE test_parse_errors_total counter
+test_parse_errors_total{field="tx_power",target="10.0.0.1"} %d
+`, i)), "test_parse_errors_total")
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestCollector_EmptyCages(t *testing.T) {
+	result := &DDMResult{
+		SysName: "sw",
+		Metrics: []DDMMetrics{
+			{Port: "1", Interface: "1/0/1", Temperature: 40, Vendor: "FS", IfIndex: "49153", OperStatus: 1},
+			{Port: "2", Interface: "1/0/2", IfIndex: "49154", OperStatus: 2, Empty: true},
+		},
+	}
+
+	collect := func(mode string) *Collector {
+		collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+		WithEmptyCages(mode)(collector)
+
+		return collector
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		collector := collect(EmptyCagesFlag)
+
+		err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_sfp_present h
+# TYPE test_sfp_present gauge
+test_sfp_present{device="sw",interface="1/0/1",port="1",target="10.0.0.1"} 1
+test_sfp_present{device="sw",interface="1/0/2",port="2",target="10.0.0.1"} 0
+`), "test_sfp_present")
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_port_info"))
+		assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_oper_status"))
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_temp"))
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_sfp_info"))
+		assert.Equal(t, 4, testutil.CollectAndCount(collector, "test_temp_thresh"))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		collector := collect(EmptyCagesSkip)
+
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_sfp_present"))
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_port_info"))
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_oper_status"))
+		assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_temp"))
+	})
+
+	t.Run("all", func(t *testing.T) {
+		collector := collect(EmptyCagesAll)
+
+		assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_sfp_present"))
+		assert.Equal(t, 2, testutil.CollectAndCount(collector, "test_temp"))
+		assert.Equal(t, 8, testutil.CollectAndCount(collector, "test_temp_thresh"))
+	})
+}
+
+func TestCollector_ThresholdState(t *testing.T) {
+	m := DDMMetrics{
+		Port: "1", Interface: "1/0/1",
+		RxPower: -19, RxPowerHighAlarm: 2, RxPowerLowAlarm: -20, RxPowerHighWarning: 1, RxPowerLowWarning: -18,
+		BiasCurrent: 6, BiasCurrentHighAlarm: 10, BiasCurrentLowAlarm: 2, BiasCurrentHighWarning: 9, BiasCurrentLowWarning: 3,
+	}
+
+	collector := newTestCollector(&mockSNMPClient{result: &DDMResult{SysName: "sw", Metrics: []DDMMetrics{m}}}, "10.0.0.1")
+
+	err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP test_threshold_margin h
+# TYPE test_threshold_margin gauge
+test_threshold_margin{device="sw",interface="1/0/1",measurement="bias_current",port="1",target="10.0.0.1"} 0.003
+test_threshold_margin{device="sw",interface="1/0/1",measurement="rx_power",port="1",target="10.0.0.1"} -1
+# HELP test_threshold_state h
+# TYPE test_threshold_state gauge
+test_threshold_state{device="sw",interface="1/0/1",measurement="bias_current",port="1",state="high_alarm",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="bias_current",port="1",state="high_warning",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="bias_current",port="1",state="low_alarm",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="bias_current",port="1",state="low_warning",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="bias_current",port="1",state="ok",target="10.0.0.1"} 1
+test_threshold_state{device="sw",interface="1/0/1",measurement="rx_power",port="1",state="high_alarm",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="rx_power",port="1",state="high_warning",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="rx_power",port="1",state="low_alarm",target="10.0.0.1"} 0
+test_threshold_state{device="sw",interface="1/0/1",measurement="rx_power",port="1",state="low_warning",target="10.0.0.1"} 1
+test_threshold_state{device="sw",interface="1/0/1",measurement="rx_power",port="1",state="ok",target="10.0.0.1"} 0
+`), "test_threshold_state", "test_threshold_margin")
+	assert.NoError(t, err)
+}
+
+func TestCollector_PowerWatts(t *testing.T) {
+	m := DDMMetrics{
+		Port: "1", Interface: "1/0/1",
+		TxPower: 0, RxPower: math.Inf(-1),
+		TxPowerHighAlarm: 3, TxPowerLowAlarm: -10, TxPowerHighWarning: 2, TxPowerLowWarning: -9,
+	}
+	m.setInvalid(thresholdField(FieldRxPower, "high", "alarm"))
+	m.setInvalid(thresholdField(FieldRxPower, "low", "alarm"))
+	m.setInvalid(thresholdField(FieldRxPower, "high", "warning"))
+	m.setInvalid(thresholdField(FieldRxPower, "low", "warning"))
+
+	collector := newTestCollector(&mockSNMPClient{result: &DDMResult{SysName: "sw", Metrics: []DDMMetrics{m}}}, "10.0.0.1")
+
+	values := gatherValues(t, collector)
+	assert.InDelta(t, 0.001, values["test_tx_watts"], 1e-9)
+	assert.Zero(t, values["test_rx_watts"])
+	assert.True(t, math.IsInf(values["test_rx"], -1))
+
+	assert.Equal(t, 4, testutil.CollectAndCount(collector, "test_tx_watts_thresh"))
+	assert.Equal(t, 0, testutil.CollectAndCount(collector, "test_rx_watts_thresh"))
+}
+
+func TestCollector_LastSuccess(t *testing.T) {
+	walked := time.Unix(1700000000, 0)
+
+	t.Run("live walk", func(t *testing.T) {
+		result := &DDMResult{Time: walked, SysName: "sw", Metrics: []DDMMetrics{{Port: "1"}}}
+		collector := newTestCollector(&mockSNMPClient{result: result}, "10.0.0.1")
+
+		values := gatherValues(t, collector)
+		assert.InDelta(t, 1700000000, values["test_last_success"], 0)
+	})
+
+	t.Run("failed live walk", func(t *testing.T) {
+		collector := newTestCollector(&mockSNMPClient{err: ErrNoData}, "10.0.0.1")
+
+		assert.Equal(t, 0, testutil.CollectAndCount(collector, "test_last_success"))
+	})
+
+	t.Run("failed poll", func(t *testing.T) {
+		cached := cachedResult{lastSuccess: walked, err: ErrNoData}
+		collector := newTestCollector(cached, "10.0.0.1")
+
+		values := gatherValues(t, collector)
+		assert.InDelta(t, 0, values["test_up"], 0)
+		assert.InDelta(t, 1700000000, values["test_last_success"], 0)
+	})
+
+	t.Run("cached result counts parse errors once", func(t *testing.T) {
+		m := DDMMetrics{Port: "1", ParseErrors: []string{FieldTxPower}}
+		result := &DDMResult{Time: walked, SysName: "sw", Metrics: []DDMMetrics{m}}
+		counts := NewParseErrorCounts()
+
+		for range 3 {
+			collector := newTestCollector(cachedResult{result: result, lastSuccess: walked}, "10.0.0.1")
+			WithParseErrorCounts(counts)(collector)
+
+			values := gatherValues(t, collector)
+			assert.InDelta(t, 1, values["test_parse_errors_total"], 0)
+		}
+	})
+}
+
 func TestCollector_Describe(t *testing.T) {
 	collector := NewCollector(&SNMPClient{}, "192.168.1.1")
 
-	ch := make(chan *prometheus.Desc, 20)
+	ch := make(chan *prometheus.Desc, 40)
 
 	go func() {
 		collector.Describe(ch)
@@ -113,18 +546,59 @@ func TestCollector_Describe(t *testing.T) {
 		count++
 	}
 
-	// 5 current + 3 config + 3 status + 5 thresholds = 16
-	assert.Equal(t, 16, count)
+	// 6 health + 4 system + 7 current + 3 info + 2 module + 3 IF-MIB + 3 config + 3 status + 9 thresholds = 40
+	assert.Equal(t, 40, count)
 }
 
 //nolint:dupl // test helper intentionally mirrors NewCollector with test-specific metric names
 func newTestCollector(mock SNMPGetter, target string) *Collector {
-	labels := []string{"device", "target", "port"}
-	thresholdLabels := []string{"device", "target", "port", "level", "type"}
+	labels := []string{"device", "target", "port", "interface"}
+	thresholdLabels := []string{"device", "target", "port", "interface", "level", "type"}
 
 	return &Collector{
 		snmpClient: mock,
 		target:     target,
+		emptyCages: EmptyCagesFlag,
+		up: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_up", Help: "h"},
+			[]string{"target"},
+		),
+		scrapeDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_scrape_duration", Help: "h"},
+			[]string{"target"},
+		),
+		scrapePDUs: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_scrape_pdus", Help: "h"},
+			[]string{"target"},
+		),
+		scrapeError: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_scrape_error", Help: "h"},
+			[]string{"target", "reason"},
+		),
+		lastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_last_success", Help: "h"},
+			[]string{"target"},
+		),
+		parseErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_parse_errors_total", Help: "h"},
+			[]string{"target", "field"},
+		),
+		switchInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_switch_info", Help: "h"},
+			[]string{"device", "target", "model", "firmware", "hardware", "sys_object_id"},
+		),
+		uptime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_uptime", Help: "h"},
+			[]string{"device", "target"},
+		),
+		cpuUtil: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_cpu_util", Help: "h"},
+			[]string{"device", "target", "unit"},
+		),
+		memoryUtil: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_memory_util", Help: "h"},
+			[]string{"device", "target", "unit"},
+		),
 		temp: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "test_temp", Help: "h"},
 			labels,
@@ -145,6 +619,49 @@ func newTestCollector(mock SNMPGetter, target string) *Collector {
 			prometheus.GaugeOpts{Name: "test_rx", Help: "h"},
 			labels,
 		),
+		txWatts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_tx_watts", Help: "h"},
+			labels,
+		),
+		rxWatts: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_rx_watts", Help: "h"},
+			labels,
+		),
+		portInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_port_info", Help: "h"},
+			[]string{"device", "target", "port", "interface", "unit", "slot"},
+		),
+		sfpPresent: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_sfp_present", Help: "h"},
+			labels,
+		),
+		sfpInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_sfp_info", Help: "h"},
+			[]string{
+				"device", "target", "port", "interface",
+				"vendor", "part_number", "serial", "revision", "wavelength_nm", "connector", "media_type",
+			},
+		),
+		moduleChanges: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "test_module_changes_total", Help: "h"},
+			labels,
+		),
+		moduleInstalled: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_module_installed", Help: "h"},
+			labels,
+		),
+		ifInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_if_info", Help: "h"},
+			[]string{"device", "target", "port", "interface", "ifIndex", "ifName", "ifAlias"},
+		),
+		operStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_oper_status", Help: "h"},
+			labels,
+		),
+		speed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_speed", Help: "h"},
+			labels,
+		),
 		ddmEnabled: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "test_ddm_enabled", Help: "h"},
 			labels,
@@ -155,7 +672,7 @@ func newTestCollector(mock SNMPGetter, target string) *Collector {
 		),
 		portLAG: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "test_lag", Help: "h"},
-			[]string{"device", "target", "port", "lag"},
+			[]string{"device", "target", "port", "interface", "lag"},
 		),
 		ddmSupported: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{Name: "test_ddm_supported", Help: "h"},
@@ -189,5 +706,21 @@ func newTestCollector(mock SNMPGetter, target string) *Collector {
 			prometheus.GaugeOpts{Name: "test_rx_thresh", Help: "h"},
 			thresholdLabels,
 		),
+		txWattsThreshold: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_tx_watts_thresh", Help: "h"},
+			thresholdLabels,
+		),
+		rxWattsThreshold: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_rx_watts_thresh", Help: "h"},
+			thresholdLabels,
+		),
+		thresholdState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_threshold_state", Help: "h"},
+			[]string{"device", "target", "port", "interface", "measurement", "state"},
+		),
+		thresholdMargin: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: "test_threshold_margin", Help: "h"},
+			[]string{"device", "target", "port", "interface", "measurement"},
+		),
 	}
 }
@@ -0,0 +1,373 @@
+package tplinkddm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the exporter configuration file, holding named auth profiles and
+// the switches to scrape.
+type Config struct {
+	Auths        map[string]Auth `yaml:"auths"`
+	Targets      []TargetConfig  `yaml:"targets"`
+	TargetGroups []TargetGroup   `yaml:"target_groups,omitempty"`
+	FileSD       []FileSDConfig  `yaml:"file_sd_configs,omitempty"`
+
+	// discovery holds the targets discovered from FileSD, set by the
+	// ConfigReloader
+	discovery *FileDiscovery
+}
+
+// TargetConfig describes a single switch. Name is what Prometheus passes as
+// the target parameter, and defaults to Address. Address is parsed with
+// ParseAddress, so it can include a transport scheme and port.
+type TargetConfig struct {
+	Labels  map[string]string `yaml:"labels,omitempty"`
+	Retries *int              `yaml:"retries,omitempty"`
+	Name    string            `yaml:"name,omitempty"`
+	Address string            `yaml:"address"`
+	Auth    string            `yaml:"auth,omitempty"`
+	// Groups names the groups the target belongs to, for scraping them
+	// together with /scrape?group=
+	Groups []string `yaml:"groups,omitempty"`
+	// EmptyCages overrides how ports without a transceiver are exported
+	// (flag, skip or all)
+	EmptyCages     string        `yaml:"empty_cages,omitempty"`
+	Timeout        time.Duration `yaml:"timeout,omitempty"`
+	MaxRepetitions uint32        `yaml:"max_repetitions,omitempty"`
+	Port           uint16        `yaml:"port,omitempty"`
+}
+
+// yamlAuth is the on-disk form of Auth, using the same keys as
+// snmp_exporter's auths. Each secret can instead be read from a file or an
+// environment variable, with the _file and _env keys.
+type yamlAuth struct {
+	Community        string `yaml:"community,omitempty"`
+	CommunityFile    string `yaml:"community_file,omitempty"`
+	CommunityEnv     string `yaml:"community_env,omitempty"`
+	SecurityLevel    string `yaml:"security_level,omitempty"`
+	Username         string `yaml:"username,omitempty"`
+	Password         string `yaml:"password,omitempty"`
+	PasswordFile     string `yaml:"password_file,omitempty"`
+	PasswordEnv      string `yaml:"password_env,omitempty"`
+	AuthProtocol     string `yaml:"auth_protocol,omitempty"`
+	PrivProtocol     string `yaml:"priv_protocol,omitempty"`
+	PrivPassword     string `yaml:"priv_password,omitempty"`
+	PrivPasswordFile string `yaml:"priv_password_file,omitempty"`
+	PrivPasswordEnv  string `yaml:"priv_password_env,omitempty"`
+	ContextName      string `yaml:"context_name,omitempty"`
+	Version          int    `yaml:"version,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (a *Auth) UnmarshalYAML(value *yaml.Node) error {
+	var y yamlAuth
+	if err := value.Decode(&y); err != nil {
+		return err //nolint:wrapcheck // yaml errors carry line numbers already
+	}
+
+	community, err := loadSecret("community", y.Community, y.CommunityFile, y.CommunityEnv)
+	if err != nil {
+		return err
+	}
+
+	password, err := loadSecret("password", y.Password, y.PasswordFile, y.PasswordEnv)
+	if err != nil {
+		return err
+	}
+
+	privPassword, err := loadSecret("priv_password", y.PrivPassword, y.PrivPasswordFile, y.PrivPasswordEnv)
+	if err != nil {
+		return err
+	}
+
+	*a = Auth{
+		Community:     community,
+		Username:      y.Username,
+		SecurityLevel: y.SecurityLevel,
+		AuthProtocol:  y.AuthProtocol,
+		AuthPassword:  password,
+		PrivProtocol:  y.PrivProtocol,
+		PrivPassword:  privPassword,
+		ContextName:   y.ContextName,
+		Version:       y.Version,
+	}
+
+	if a.Version == 0 {
+		a.Version = 2
+	}
+
+	return nil
+}
+
+// loadSecret returns the secret given inline, or read from file (without
+// its trailing newline) or the env environment variable. At most one of them
+// may be set.
+func loadSecret(key, inline, file, env string) (string, error) {
+	set := 0
+
+	for _, v := range []string{inline, file, env} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set > 1 {
+		return "", fmt.Errorf("only one of %[1]s, %[1]s_file and %[1]s_env may be set", key)
+	}
+
+	switch {
+	case file != "":
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("read %s_file: %w", key, err)
+		}
+
+		return strings.TrimRight(string(b), "\r\n"), nil
+	case env != "":
+		v, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("%s_env: environment variable %s is not set", key, env)
+		}
+
+		return v, nil
+	default:
+		return inline, nil
+	}
+}
+
+// reservedLabels can't be set as extra target labels, since the collector
+// already sets them.
+//
+//nolint:gochecknoglobals // lookup table
+var reservedLabels = map[string]bool{
+	"device": true, "target": true, "port": true, "interface": true, "unit": true, "slot": true,
+	"level": true, "type": true, "lag": true, "ifIndex": true, "ifName": true, "ifAlias": true,
+	"vendor": true, "part_number": true, "serial": true, "revision": true, "wavelength_nm": true,
+	"connector": true, "media_type": true, "model": true, "firmware": true, "hardware": true,
+	"sys_object_id": true, "measurement": true, "state": true,
+	"field": true, "reason": true,
+}
+
+// LoadConfig reads and validates a YAML configuration file
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	return ParseConfig(b)
+}
+
+// ParseConfig parses and validates a YAML configuration
+func ParseConfig(b []byte) (*Config, error) {
+	cfg := &Config{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	for name, auth := range c.Auths {
+		if err := auth.Validate(); err != nil {
+			return fmt.Errorf("auth %q: %w", name, err)
+		}
+	}
+
+	seen := map[string]bool{}
+
+	for i := range c.Targets {
+		t := &c.Targets[i]
+
+		if t.Address == "" {
+			return fmt.Errorf("target %d: address is required", i)
+		}
+
+		if t.Name == "" {
+			t.Name = t.Address
+		}
+
+		if seen[t.Name] {
+			return fmt.Errorf("target %q: duplicate name", t.Name)
+		}
+
+		seen[t.Name] = true
+
+		addr, err := ParseAddress(t.Address)
+		if err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+
+		if addr.Port != 0 && t.Port != 0 {
+			return fmt.Errorf("target %q: port given in both address and port", t.Name)
+		}
+
+		if err := c.validateSettings(t); err != nil {
+			return fmt.Errorf("target %q: %w", t.Name, err)
+		}
+	}
+
+	for i := range c.TargetGroups {
+		if err := c.validateGroup(i, &c.TargetGroups[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.FileSD {
+		if err := c.validateFileSD(i, &c.FileSD[i]); err != nil {
+			return fmt.Errorf("file_sd_configs %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// validateSettings checks the settings shared by targets and target groups
+func (c *Config) validateSettings(t *TargetConfig) error {
+	if _, ok := c.Auths[t.Auth]; t.Auth != "" && !ok {
+		return fmt.Errorf("unknown auth %q", t.Auth)
+	}
+
+	if t.Retries != nil && *t.Retries < 0 {
+		return errors.New("retries must not be negative")
+	}
+
+	if t.EmptyCages != "" && !ValidEmptyCages(t.EmptyCages) {
+		return fmt.Errorf("unsupported empty_cages %q (want flag, skip or all)", t.EmptyCages)
+	}
+
+	if slices.Contains(t.Groups, "") {
+		return errors.New("group names must not be empty")
+	}
+
+	for k := range t.Labels {
+		// labels starting with __ are Prometheus' own, and would clash with
+		// the service discovery parameters
+		if reservedLabels[k] || strings.HasPrefix(k, "__") {
+			return fmt.Errorf("label %q is reserved", k)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateGroup(i int, g *TargetGroup) error {
+	if g.Name == "" {
+		g.Name = g.SRV + g.A
+	}
+
+	switch {
+	case (g.SRV == "") == (g.A == ""):
+		return fmt.Errorf("target group %d: exactly one of srv or a is required", i)
+	case g.Address != "":
+		return fmt.Errorf("target group %q: address is set by DNS, not in the group", g.Name)
+	case g.SRV != "" && g.Port != 0:
+		return fmt.Errorf("target group %q: port is set by the SRV records", g.Name)
+	}
+
+	if err := c.validateSettings(&g.TargetConfig); err != nil {
+		return fmt.Errorf("target group %q: %w", g.Name, err)
+	}
+
+	return nil
+}
+
+func (c *Config) validateFileSD(i int, f *FileSDConfig) error {
+	if len(f.Files) == 0 {
+		return errors.New("files is required")
+	}
+
+	for _, pattern := range f.Files {
+		if err := validFileSDPattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	if f.Address != "" || f.Name != "" {
+		return errors.New("name and address are set by the files")
+	}
+
+	return c.validateSettings(&f.TargetConfig)
+}
+
+// Target returns the configured target with the given name, if any
+func (c *Config) Target(name string) (TargetConfig, bool) {
+	if c == nil {
+		return TargetConfig{}, false
+	}
+
+	for _, t := range c.Targets {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return TargetConfig{}, false
+}
+
+// Auth returns the named auth profile, if any
+func (c *Config) Auth(name string) (Auth, bool) {
+	if c == nil {
+		return Auth{}, false
+	}
+
+	a, ok := c.Auths[name]
+
+	return a, ok
+}
+
+// NewClient creates an SNMP client for the target's address, using its
+// configured client settings and any extra options
+func (t TargetConfig) NewClient(auth Auth, extra ...ClientOption) (*SNMPClient, error) {
+	addr, err := ParseAddress(t.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append(addr.ClientOptions(), t.ClientOptions()...)
+	opts = append(opts, extra...)
+
+	return NewSNMPClientWithAuth(addr.Host, auth, opts...), nil
+}
+
+// ClientOptions returns the SNMP client options for the target's configured
+// port, timeout, retries and max repetitions.
+func (t TargetConfig) ClientOptions() []ClientOption {
+	var opts []ClientOption
+
+	if t.Port != 0 {
+		opts = append(opts, WithPort(t.Port))
+	}
+
+	if t.Timeout != 0 {
+		opts = append(opts, WithTimeout(t.Timeout))
+	}
+
+	if t.Retries != nil {
+		opts = append(opts, WithRetries(*t.Retries))
+	}
+
+	if t.MaxRepetitions != 0 {
+		opts = append(opts, WithMaxRepetitions(t.MaxRepetitions))
+	}
+
+	return opts
+}
@@ -0,0 +1,257 @@
+package tplinkddm
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+auths:
+  public_v2:
+    community: public
+  switches_v3:
+    version: 3
+    username: monitor
+    security_level: authPriv
+    auth_protocol: SHA256
+    password: authpassword
+    priv_protocol: AES
+    priv_password: privpassword
+targets:
+  - name: core-1
+    address: 10.0.0.1
+    auth: switches_v3
+    port: 1161
+    timeout: 5s
+    retries: 3
+    max_repetitions: 20
+    empty_cages: skip
+    labels:
+      site: ams1
+      rack: r12
+      role: core
+  - address: 10.0.0.2
+    auth: public_v2
+target_groups:
+  - srv: _snmp._udp.switches.example.com
+    auth: switches_v3
+    labels:
+      site: ams1
+  - a: edge.example.com
+    port: 1161
+`
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(testConfig))
+	require.NoError(t, err)
+
+	require.Len(t, cfg.Auths, 2)
+	assert.Equal(t, Auth{Version: 2, Community: "public"}, cfg.Auths["public_v2"])
+	assert.Equal(t, Auth{
+		Version: 3, Username: "monitor", SecurityLevel: SecurityLevelAuthPriv,
+		AuthProtocol: "SHA256", AuthPassword: "authpassword",
+		PrivProtocol: "AES", PrivPassword: "privpassword",
+	}, cfg.Auths["switches_v3"])
+
+	core, ok := cfg.Target("core-1")
+	require.True(t, ok)
+	assert.Equal(t, "10.0.0.1", core.Address)
+	assert.Equal(t, uint16(1161), core.Port)
+	assert.Equal(t, 5*time.Second, core.Timeout)
+	require.NotNil(t, core.Retries)
+	assert.Equal(t, 3, *core.Retries)
+	assert.Equal(t, uint32(20), core.MaxRepetitions)
+	assert.Equal(t, EmptyCagesSkip, core.EmptyCages)
+	assert.Equal(t, map[string]string{"site": "ams1", "rack": "r12", "role": "core"}, core.Labels)
+
+	// name defaults to the address
+	edge, ok := cfg.Target("10.0.0.2")
+	require.True(t, ok)
+	assert.Equal(t, "public_v2", edge.Auth)
+
+	_, ok = cfg.Target("10.0.0.3")
+	assert.False(t, ok)
+
+	auth, ok := cfg.Auth("switches_v3")
+	assert.True(t, ok)
+	assert.Equal(t, "monitor", auth.Username)
+
+	_, ok = cfg.Auth("missing")
+	assert.False(t, ok)
+
+	// groups are named after their DNS name by default
+	require.Len(t, cfg.TargetGroups, 2)
+	assert.Equal(t, "_snmp._udp.switches.example.com", cfg.TargetGroups[0].SRV)
+	assert.Equal(t, "_snmp._udp.switches.example.com", cfg.TargetGroups[0].Name)
+	assert.Equal(t, "switches_v3", cfg.TargetGroups[0].Auth)
+	assert.Equal(t, "edge.example.com", cfg.TargetGroups[1].A)
+	assert.Equal(t, uint16(1161), cfg.TargetGroups[1].Port)
+}
+
+func TestParseConfig_Empty(t *testing.T) {
+	cfg, err := ParseConfig(nil)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Targets)
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"unknown field", "auths: {}\nswitches: []\n"},
+		{"invalid auth", "auths:\n  bad:\n    version: 3\n"},
+		{"missing address", "targets:\n  - name: foo\n"},
+		{"duplicate name", "targets:\n  - address: 10.0.0.1\n  - address: 10.0.0.1\n"},
+		{"unknown auth", "targets:\n  - address: 10.0.0.1\n    auth: nope\n"},
+		{"negative retries", "targets:\n  - address: 10.0.0.1\n    retries: -1\n"},
+		{"bad address", "targets:\n  - address: ftp://10.0.0.1\n"},
+		{"port given twice", "targets:\n  - address: 10.0.0.1:1161\n    port: 161\n"},
+		{"bad empty_cages", "targets:\n  - address: 10.0.0.1\n    empty_cages: hide\n"},
+		{"reserved label", "targets:\n  - address: 10.0.0.1\n    labels:\n      port: '1'\n"},
+		{"internal label", "targets:\n  - address: 10.0.0.1\n    labels:\n      __param_auth: v3\n"},
+		{"bad timeout", "targets:\n  - address: 10.0.0.1\n    timeout: soon\n"},
+		{"empty group name", "targets:\n  - address: 10.0.0.1\n    groups: ['']\n"},
+		{"file_sd without files", "file_sd_configs:\n  - auth: v2\n"},
+		{"file_sd bad pattern", "file_sd_configs:\n  - files: ['/etc/[.json']\n"},
+		{"file_sd not json or yaml", "file_sd_configs:\n  - files: [/etc/switches.txt]\n"},
+		{"file_sd with address", "file_sd_configs:\n  - files: [/etc/switches.json]\n    address: 10.0.0.1\n"},
+		{"file_sd with unknown auth", "file_sd_configs:\n  - files: [/etc/switches.json]\n    auth: nope\n"},
+		{"group without records", "target_groups:\n  - auth: nope\n"},
+		{"group with srv and a", "target_groups:\n  - srv: _snmp._udp.example.com\n    a: example.com\n"},
+		{"group with address", "target_groups:\n  - a: example.com\n    address: 10.0.0.1\n"},
+		{"srv group with port", "target_groups:\n  - srv: _snmp._udp.example.com\n    port: 161\n"},
+		{"group with unknown auth", "target_groups:\n  - a: example.com\n    auth: nope\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0o600))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Targets, 2)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yml"))
+	assert.Error(t, err)
+}
+
+func TestParseConfig_SecretsFromFilesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "community"), []byte("s3cret\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "priv"), []byte("privpassword"), 0o600))
+	t.Setenv("TEST_SNMP_PASSWORD", "authpassword")
+
+	cfg, err := ParseConfig([]byte(`
+auths:
+  v2:
+    community_file: ` + filepath.Join(dir, "community") + `
+  v3:
+    version: 3
+    username: monitor
+    password_env: TEST_SNMP_PASSWORD
+    priv_password_file: ` + filepath.Join(dir, "priv") + `
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, Auth{Version: 2, Community: "s3cret"}, cfg.Auths["v2"])
+	assert.Equal(t, "authpassword", cfg.Auths["v3"].AuthPassword)
+	assert.Equal(t, "privpassword", cfg.Auths["v3"].PrivPassword)
+
+	for name, config := range map[string]string{
+		"missing file":   "auths:\n  v2:\n    community_file: " + filepath.Join(dir, "missing") + "\n",
+		"unset variable": "auths:\n  v2:\n    community_env: TEST_SNMP_UNSET\n",
+		"inline and env": "auths:\n  v2:\n    community: public\n    community_env: TEST_SNMP_PASSWORD\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(config))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfig_NilLookups(t *testing.T) {
+	var cfg *Config
+
+	_, ok := cfg.Target("x")
+	assert.False(t, ok)
+
+	_, ok = cfg.Auth("x")
+	assert.False(t, ok)
+}
+
+func TestTargetConfig_NewClient(t *testing.T) {
+	client, err := TargetConfig{Address: "tcp://[2001:db8::1]:1161", Timeout: 5 * time.Second}.NewClient(Auth{Version: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", client.target)
+	assert.Equal(t, TransportTCP, client.transport)
+	assert.Equal(t, uint16(1161), client.port)
+	assert.Equal(t, 5*time.Second, client.timeout)
+
+	client, err = TargetConfig{Address: "10.0.0.1", Port: 1161}.NewClient(Auth{Version: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", client.target)
+	assert.Equal(t, TransportUDP, client.transport)
+	assert.Equal(t, uint16(1161), client.port)
+
+	_, err = TargetConfig{Address: "ftp://10.0.0.1"}.NewClient(Auth{Version: 2})
+	assert.Error(t, err)
+}
+
+func TestTargetConfig_ClientOptions(t *testing.T) {
+	retries := 0
+	tc := TargetConfig{Address: "10.0.0.1", Port: 1161, Timeout: 5 * time.Second, Retries: &retries, MaxRepetitions: 10}
+
+	client := NewSNMPClientWithAuth(tc.Address, Auth{Community: "public"}, tc.ClientOptions()...)
+	assert.Equal(t, uint16(1161), client.port)
+	assert.Equal(t, 5*time.Second, client.timeout)
+	assert.Equal(t, 0, client.retries)
+	assert.Equal(t, uint32(10), client.maxRepetitions)
+
+	client = NewSNMPClientWithAuth("10.0.0.1", Auth{Community: "public"}, TargetConfig{}.ClientOptions()...)
+	assert.Equal(t, uint16(161), client.port)
+	assert.Equal(t, 2*time.Second, client.timeout)
+	assert.Equal(t, 1, client.retries)
+	assert.Zero(t, client.maxRepetitions)
+}
+
+func TestReservedLabels(t *testing.T) {
+	// a target label with the same name as a collector label would make the
+	// scrape's registration fail, so the reserved set must match the labels
+	// the collector uses
+	ch := make(chan *prometheus.Desc, 100)
+	NewCollector(&SNMPClient{}, "192.168.1.1").Describe(ch)
+	close(ch)
+
+	variableLabels := regexp.MustCompile(`variableLabels: \{([^}]*)\}`)
+	used := map[string]bool{}
+
+	for desc := range ch {
+		m := variableLabels.FindStringSubmatch(desc.String())
+		require.NotNil(t, m, desc.String())
+
+		for l := range strings.SplitSeq(m[1], ",") {
+			if l != "" {
+				used[l] = true
+			}
+		}
+	}
+
+	assert.Equal(t, used, reservedLabels)
+}
@@ -0,0 +1,203 @@
+package tplinkddm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// oidTPLinkEnterprise is TP-Link's enterprise number, under which its
+// switches' sysObjectIDs live
+const oidTPLinkEnterprise = "1.3.6.1.4.1.11863"
+
+// maxSweepAddresses bounds the size of the prefixes a sweep accepts, so a
+// typo like /8 doesn't send millions of probes
+const maxSweepAddresses = 1 << 16
+
+// ErrNotSNMP is returned by Probe when nothing answers SNMP at the address
+var ErrNotSNMP = errors.New("no SNMP response")
+
+// ProbeResult is what a probe found at an address
+type ProbeResult struct {
+	Address     string
+	SysName     string
+	SysObjectID string
+	TPLink      bool // sysObjectID is under TP-Link's enterprise
+	DDM         bool // the DDM MIB responds
+}
+
+// Probe checks whether the target is a TP-Link switch with the DDM MIB,
+// with a Get of sysObjectID and sysName and, for TP-Link devices, a GetNext
+// into the DDM MIB. It's much lighter than a walk, for sweeping subnets.
+func (c *SNMPClient) Probe(ctx context.Context) (*ProbeResult, error) {
+	ctx, span := tracer.Start(ctx, "SNMPClient.Probe")
+	defer span.End()
+
+	client, err := c.dial(ctx, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = client.Conn.Close() }()
+
+	result, err := client.Get([]string{oidSysObjectID, oidSysName})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotSNMP, err)
+	}
+
+	probe := &ProbeResult{Address: c.target}
+
+	for _, pdu := range result.Variables {
+		//nolint:exhaustive // NoSuchObject and friends are skipped
+		switch pdu.Type {
+		case gosnmp.ObjectIdentifier:
+			probe.SysObjectID = strings.TrimPrefix(pdu.Value.(string), ".")
+		case gosnmp.OctetString:
+			probe.SysName = strings.TrimSpace(string(pdu.Value.([]byte)))
+		}
+	}
+
+	probe.TPLink = strings.HasPrefix(probe.SysObjectID, oidTPLinkEnterprise+".")
+	if !probe.TPLink {
+		return probe, nil
+	}
+
+	next, err := client.GetNext([]string{oidDDMRoot})
+	if err != nil {
+		slog.DebugContext(ctx, "DDM probe failed", "target", c.target, "error", err)
+
+		return probe, nil
+	}
+
+	for _, pdu := range next.Variables {
+		if strings.HasPrefix(strings.TrimPrefix(pdu.Name, "."), oidDDMRoot+".") && pdu.Type != gosnmp.EndOfMibView {
+			probe.DDM = true
+		}
+	}
+
+	return probe, nil
+}
+
+// SweepAddresses returns the host addresses in the prefixes, leaving out the
+// network and broadcast addresses of IPv4 prefixes shorter than /31
+func SweepAddresses(prefixes []netip.Prefix) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+
+	for _, p := range prefixes {
+		p = p.Masked()
+
+		hostBits := p.Addr().BitLen() - p.Bits()
+		if hostBits > 16 {
+			return nil, fmt.Errorf("prefix %s is too large to sweep (at most %d addresses)", p, maxSweepAddresses)
+		}
+
+		first := p.Addr()
+		if p.Addr().Is4() && hostBits > 1 {
+			first = first.Next()
+		}
+
+		for a := first; a.IsValid() && p.Contains(a); a = a.Next() {
+			// the broadcast address is the last in the prefix
+			if p.Addr().Is4() && hostBits > 1 && !p.Contains(a.Next()) {
+				break
+			}
+
+			addrs = append(addrs, a)
+		}
+	}
+
+	if len(addrs) > maxSweepAddresses {
+		return nil, fmt.Errorf("%d addresses are too many to sweep (at most %d)", len(addrs), maxSweepAddresses)
+	}
+
+	return addrs, nil
+}
+
+// Sweep probes the addresses with up to workers probes at once, using the
+// client newClient returns for each, and returns the TP-Link devices found,
+// in address order. Addresses which don't answer are skipped.
+func Sweep(ctx context.Context, addrs []netip.Addr, workers int, newClient func(host string) *SNMPClient) []ProbeResult {
+	var (
+		found []ProbeResult
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	work := make(chan netip.Addr)
+
+	for range max(workers, 1) {
+		wg.Go(func() {
+			for addr := range work {
+				probe, err := newClient(addr.String()).Probe(ctx)
+				if err != nil {
+					slog.DebugContext(ctx, "no SNMP response", "address", addr, "error", err)
+
+					continue
+				}
+
+				if !probe.TPLink {
+					slog.DebugContext(ctx, "not a TP-Link device", "address", addr, "sys_object_id", probe.SysObjectID)
+
+					continue
+				}
+
+				mu.Lock()
+				found = append(found, *probe)
+				mu.Unlock()
+			}
+		})
+	}
+
+	for _, addr := range addrs {
+		select {
+		case work <- addr:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	close(work)
+	wg.Wait()
+
+	slices.SortFunc(found, func(a, b ProbeResult) int {
+		return netip.MustParseAddr(a.Address).Compare(netip.MustParseAddr(b.Address))
+	})
+
+	return found
+}
+
+// DiscoveredTargets returns the devices with the DDM MIB as file_sd target
+// groups, one per device, with the device's sysName and sysObjectID as
+// __meta_ labels and auth, if given, as __param_auth
+func DiscoveredTargets(found []ProbeResult, auth string) []SDTargetGroup {
+	groups := make([]SDTargetGroup, 0, len(found))
+
+	for _, p := range found {
+		if !p.DDM {
+			continue
+		}
+
+		labels := map[string]string{
+			"__meta_tplink_sys_name":      p.SysName,
+			"__meta_tplink_sys_object_id": p.SysObjectID,
+		}
+
+		if auth != "" {
+			labels[fileSDAuthLabel] = auth
+		}
+
+		groups = append(groups, SDTargetGroup{Targets: []string{p.Address}, Labels: labels})
+	}
+
+	return groups
+}
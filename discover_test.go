@@ -0,0 +1,150 @@
+package tplinkddm
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepAddresses(t *testing.T) {
+	tests := []struct {
+		prefixes []string
+		want     []string
+	}{
+		{[]string{"192.0.2.0/30"}, []string{"192.0.2.1", "192.0.2.2"}},
+		{[]string{"192.0.2.7/29"}, []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5", "192.0.2.6"}},
+		{[]string{"192.0.2.0/31"}, []string{"192.0.2.0", "192.0.2.1"}},
+		{[]string{"192.0.2.9/32", "2001:db8::/127"}, []string{"192.0.2.9", "2001:db8::", "2001:db8::1"}},
+	}
+
+	for _, tt := range tests {
+		prefixes := make([]netip.Prefix, 0, len(tt.prefixes))
+		for _, p := range tt.prefixes {
+			prefixes = append(prefixes, netip.MustParsePrefix(p))
+		}
+
+		addrs, err := SweepAddresses(prefixes)
+		require.NoError(t, err)
+
+		got := make([]string, 0, len(addrs))
+		for _, a := range addrs {
+			got = append(got, a.String())
+		}
+
+		assert.Equal(t, tt.want, got, tt.prefixes)
+	}
+
+	_, err := SweepAddresses([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	require.Error(t, err)
+
+	_, err = SweepAddresses([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/16"), netip.MustParsePrefix("10.1.0.0/16")})
+	require.Error(t, err)
+}
+
+// probeVars returns the vars for an agent with the given sysObjectID, and
+// the DDM table if ddm is set
+func probeVars(objectID string, ddm bool) []gosnmp.SnmpPDU {
+	vars := []gosnmp.SnmpPDU{
+		{Name: "." + oidSysName, Type: gosnmp.OctetString, Value: []byte("probe-switch")},
+		{Name: "." + oidSysObjectID, Type: gosnmp.ObjectIdentifier, Value: "." + objectID},
+	}
+
+	if ddm {
+		vars = append(vars, testDDMVars()[1:]...)
+	}
+
+	return vars
+}
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		objectID string
+		ddm      bool
+		want     ProbeResult
+	}{
+		{
+			name: "TP-Link with DDM", objectID: oidTPLinkEnterprise + ".5.170", ddm: true,
+			want: ProbeResult{SysObjectID: oidTPLinkEnterprise + ".5.170", TPLink: true, DDM: true},
+		},
+		{
+			name: "TP-Link without DDM", objectID: oidTPLinkEnterprise + ".5.1",
+			want: ProbeResult{SysObjectID: oidTPLinkEnterprise + ".5.1", TPLink: true},
+		},
+		{
+			// DDM-looking data doesn't count without TP-Link's sysObjectID
+			name: "other vendor", objectID: "1.3.6.1.4.1.9.1.1", ddm: true,
+			want: ProbeResult{SysObjectID: "1.3.6.1.4.1.9.1.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := newTestAgent(t, "public", probeVars(tt.objectID, tt.ddm))
+			client := NewSNMPClientWithAuth("127.0.0.1", Auth{Version: 2, Community: "public"}, WithPort(agent.port))
+
+			got, err := client.Probe(t.Context())
+			require.NoError(t, err)
+
+			tt.want.Address = "127.0.0.1"
+			tt.want.SysName = "probe-switch"
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	t.Parallel()
+
+	// one agent, answering on 127.0.0.1; 127.0.0.2 and .3 don't answer
+	agent := newTestAgent(t, "public", probeVars(oidTPLinkEnterprise+".5.170", true))
+
+	addrs, err := SweepAddresses([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/30"), netip.MustParsePrefix("127.0.0.3/32")})
+	require.NoError(t, err)
+
+	found := Sweep(t.Context(), addrs, 4, func(host string) *SNMPClient {
+		return NewSNMPClientWithAuth(host, Auth{Version: 2, Community: "public"},
+			WithPort(agent.port), WithTimeout(200*time.Millisecond), WithRetries(0))
+	})
+
+	require.Len(t, found, 1)
+	assert.Equal(t, "127.0.0.1", found[0].Address)
+	assert.True(t, found[0].DDM)
+
+	assert.Equal(t, []SDTargetGroup{{
+		Targets: []string{"127.0.0.1"},
+		Labels: map[string]string{
+			"__meta_tplink_sys_name":      "probe-switch",
+			"__meta_tplink_sys_object_id": oidTPLinkEnterprise + ".5.170",
+			"__param_auth":                "public_v2",
+		},
+	}}, DiscoveredTargets(found, "public_v2"))
+
+	// devices without the DDM MIB aren't targets
+	assert.Empty(t, DiscoveredTargets([]ProbeResult{{Address: "10.0.0.1", TPLink: true}}, ""))
+}
+
+func TestProbe_NoAnswer(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	port := uint16(conn.LocalAddr().(*net.UDPAddr).Port) //nolint:gosec // port is always in range
+	client := NewSNMPClientWithAuth("127.0.0.1", Auth{Version: 2, Community: "public"},
+		WithPort(port), WithTimeout(100*time.Millisecond), WithRetries(0))
+
+	_, err = client.Probe(t.Context())
+	require.ErrorIs(t, err, ErrNotSNMP)
+}
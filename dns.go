@@ -0,0 +1,321 @@
+package tplinkddm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dnsIdleTimeout is how long an answer stays cached, past its expiry, without
+// being used. Expired answers are kept as a fallback for failed lookups, but
+// names which are no longer asked for, e.g. one-off targets from the /scrape
+// query string, are dropped.
+const dnsIdleTimeout = time.Hour
+
+// DNS record types, used as the type label of the resolver metrics
+const (
+	dnsTypeHost = "A"
+	dnsTypeSRV  = "SRV"
+)
+
+// TargetGroup is a set of switches listed in DNS, either as the SRV records
+// of one name, or as the A/AAAA records of one name. Each record becomes a
+// target with the group's settings. Name only identifies the group in logs
+// and errors; Address must be empty, since it comes from DNS.
+type TargetGroup struct {
+	SRV          string `yaml:"srv,omitempty"`
+	A            string `yaml:"a,omitempty"`
+	TargetConfig `yaml:",inline"`
+}
+
+// dnsLookup is the subset of net.Resolver used by Resolver
+type dnsLookup interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Resolver looks up switch hostnames and target groups, caching answers for
+// a fixed TTL. If a lookup fails, the last answer is used until a lookup
+// succeeds again. Answers which aren't used for an hour are dropped.
+type Resolver struct {
+	lookup   dnsLookup
+	cache    map[dnsQuery]*dnsAnswer
+	lookups  *prometheus.CounterVec
+	failures *prometheus.CounterVec
+	ttl      time.Duration
+	mu       sync.Mutex
+}
+
+type dnsQuery struct {
+	typ, name string
+}
+
+// dnsAnswer is a cached answer: IPs for A/AAAA queries, host:port pairs for
+// SRV queries
+type dnsAnswer struct {
+	expires time.Time
+	used    time.Time
+	addrs   []string
+}
+
+// NewResolver creates a Resolver using the system resolver, caching answers
+// for ttl
+func NewResolver(ttl time.Duration) *Resolver {
+	return newResolver(net.DefaultResolver, ttl)
+}
+
+func newResolver(lookup dnsLookup, ttl time.Duration) *Resolver {
+	return &Resolver{
+		lookup: lookup,
+		cache:  map[dnsQuery]*dnsAnswer{},
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tplink_ddm_exporter_dns_lookups_total",
+			Help: "Number of DNS lookups of switch hostnames and target groups, excluding cached answers",
+		}, []string{"type"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tplink_ddm_exporter_dns_lookup_failures_total",
+			Help: "Number of failed DNS lookups of switch hostnames and target groups",
+		}, []string{"type"}),
+		ttl: ttl,
+	}
+}
+
+// LookupHost returns the IP addresses of host, or host itself if it's
+// already an IP
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	return r.cached(dnsQuery{typ: dnsTypeHost, name: host}, func() ([]string, error) {
+		ips, err := r.lookup.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s: %w", host, err)
+		}
+
+		addrs := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			addrs = append(addrs, ip.String())
+		}
+
+		return addrs, nil
+	})
+}
+
+// LookupSRV returns the targets of name's SRV records as host:port pairs,
+// ordered by priority
+func (r *Resolver) LookupSRV(ctx context.Context, name string) ([]string, error) {
+	return r.cached(dnsQuery{typ: dnsTypeSRV, name: name}, func() ([]string, error) {
+		_, records, err := r.lookup.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup SRV %s: %w", name, err)
+		}
+
+		slices.SortStableFunc(records, func(a, b *net.SRV) int {
+			return int(a.Priority) - int(b.Priority)
+		})
+
+		addrs := make([]string, 0, len(records))
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+
+		return addrs, nil
+	})
+}
+
+// cached returns the cached answer to q if it hasn't expired, and otherwise
+// looks it up. A failed lookup falls back to the expired answer, if any.
+func (r *Resolver) cached(q dnsQuery, lookup func() ([]string, error)) ([]string, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	answer, ok := r.cache[q]
+
+	if ok {
+		answer.used = now
+	}
+	r.mu.Unlock()
+
+	if ok && now.Before(answer.expires) {
+		return answer.addrs, nil
+	}
+
+	r.lookups.WithLabelValues(q.typ).Inc()
+
+	addrs, err := lookup()
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("lookup %s %s: no records", q.typ, q.name)
+	}
+
+	if err != nil {
+		r.failures.WithLabelValues(q.typ).Inc()
+
+		if ok {
+			slog.Warn("DNS lookup failed, using previous answer", "name", q.name, "type", q.typ, "error", err)
+
+			return answer.addrs, nil
+		}
+
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.prune(now)
+	r.cache[q] = &dnsAnswer{addrs: addrs, expires: now.Add(r.ttl), used: now}
+	r.mu.Unlock()
+
+	return addrs, nil
+}
+
+// prune drops the expired answers which haven't been used for
+// dnsIdleTimeout. r.mu must be held.
+func (r *Resolver) prune(now time.Time) {
+	for q, answer := range r.cache {
+		if now.After(answer.expires) && now.Sub(answer.used) > dnsIdleTimeout {
+			delete(r.cache, q)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector
+func (r *Resolver) Describe(ch chan<- *prometheus.Desc) {
+	r.lookups.Describe(ch)
+	r.failures.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (r *Resolver) Collect(ch chan<- prometheus.Metric) {
+	r.lookups.Collect(ch)
+	r.failures.Collect(ch)
+}
+
+// expand looks up the group's records, returning a target for each. SRV
+// targets are named by their hostname, A/AAAA targets by their IP.
+func (g *TargetGroup) expand(ctx context.Context, r *Resolver) ([]TargetConfig, error) {
+	if g.SRV != "" {
+		addrs, err := r.LookupSRV(ctx, g.SRV)
+		if err != nil {
+			return nil, err
+		}
+
+		targets := make([]TargetConfig, 0, len(addrs))
+
+		for _, addr := range addrs {
+			host, _, _ := net.SplitHostPort(addr)
+
+			t := g.TargetConfig
+			t.Name, t.Address = host, addr
+			targets = append(targets, t)
+		}
+
+		return targets, nil
+	}
+
+	ips, err := r.LookupHost(ctx, g.A)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]TargetConfig, 0, len(ips))
+
+	for _, ip := range ips {
+		t := g.TargetConfig
+		t.Name, t.Address = ip, ip
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// ResolveTargets returns the configured targets followed by those listed in
+// DNS by the target groups, and then those discovered from file_sd files.
+// Groups which can't be looked up are logged and skipped, and targets whose
+// name is already taken are dropped.
+func (c *Config) ResolveTargets(ctx context.Context, r *Resolver) []TargetConfig {
+	if c == nil {
+		return nil
+	}
+
+	targets := slices.Clone(c.Targets)
+
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		seen[t.Name] = true
+	}
+
+	for i := range c.TargetGroups {
+		g := &c.TargetGroups[i]
+
+		expanded, err := g.expand(ctx, r)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to expand target group", "group", g.Name, "error", err)
+
+			continue
+		}
+
+		for _, t := range expanded {
+			if seen[t.Name] {
+				continue
+			}
+
+			seen[t.Name] = true
+
+			targets = append(targets, t)
+		}
+	}
+
+	for _, t := range c.discovery.Targets() {
+		if !seen[t.Name] {
+			seen[t.Name] = true
+
+			targets = append(targets, t)
+		}
+	}
+
+	return targets
+}
+
+// LookupTarget returns the target with the given name, looking in the
+// configured targets first, then in the target groups and file_sd files
+func (c *Config) LookupTarget(ctx context.Context, r *Resolver, name string) (TargetConfig, bool) {
+	if t, ok := c.Target(name); ok {
+		return t, true
+	}
+
+	if c == nil || (len(c.TargetGroups) == 0 && c.discovery == nil) {
+		return TargetConfig{}, false
+	}
+
+	for _, t := range c.ResolveTargets(ctx, r) {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return TargetConfig{}, false
+}
+
+// GroupTargets returns the targets, including those listed in DNS by the
+// target groups and discovered from file_sd files, which belong to the named
+// group
+func (c *Config) GroupTargets(ctx context.Context, r *Resolver, group string) []TargetConfig {
+	var members []TargetConfig
+
+	for _, t := range c.ResolveTargets(ctx, r) {
+		if slices.Contains(t.Groups, group) {
+			members = append(members, t)
+		}
+	}
+
+	return members
+}
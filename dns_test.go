@@ -0,0 +1,246 @@
+package tplinkddm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDNS answers lookups from fixed records, counting the lookups it gets
+type fakeDNS struct {
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+	err   error
+
+	mu      sync.Mutex
+	lookups int
+}
+
+func (d *fakeDNS) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lookups++
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	addrs := make([]net.IPAddr, 0, len(d.hosts[host]))
+	for _, ip := range d.hosts[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	return addrs, nil
+}
+
+func (d *fakeDNS) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lookups++
+
+	if d.err != nil {
+		return "", nil, d.err
+	}
+
+	return name, d.srv[name], nil
+}
+
+func (d *fakeDNS) fail(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.err = err
+}
+
+func (d *fakeDNS) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lookups
+}
+
+func TestResolver_LookupHost(t *testing.T) {
+	dns := &fakeDNS{hosts: map[string][]string{"sw1.example.com": {"10.0.0.1", "2001:db8::1"}}}
+	r := newResolver(dns, time.Hour)
+
+	addrs, err := r.LookupHost(t.Context(), "sw1.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "2001:db8::1"}, addrs)
+
+	// cached
+	_, err = r.LookupHost(t.Context(), "sw1.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, dns.count())
+	assert.InDelta(t, 1, testutil.ToFloat64(r.lookups.WithLabelValues(dnsTypeHost)), 0)
+
+	// IPs aren't looked up
+	addrs, err = r.LookupHost(t.Context(), "10.0.0.9")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9"}, addrs)
+	assert.Equal(t, 1, dns.count())
+
+	// a name without records is an error
+	_, err = r.LookupHost(t.Context(), "missing.example.com")
+	require.Error(t, err)
+	assert.InDelta(t, 1, testutil.ToFloat64(r.failures.WithLabelValues(dnsTypeHost)), 0)
+}
+
+func TestResolver_StaleOnFailure(t *testing.T) {
+	dns := &fakeDNS{hosts: map[string][]string{"sw1.example.com": {"10.0.0.1"}}}
+	r := newResolver(dns, 0)
+
+	addrs, err := r.LookupHost(t.Context(), "sw1.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+
+	// the answer has expired, so it's looked up again, and the failure
+	// falls back to the previous answer
+	dns.fail(errors.New("server misbehaving"))
+
+	addrs, err = r.LookupHost(t.Context(), "sw1.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.Equal(t, 2, dns.count())
+	assert.InDelta(t, 1, testutil.ToFloat64(r.failures.WithLabelValues(dnsTypeHost)), 0)
+
+	// without a previous answer, the failure is returned
+	_, err = r.LookupHost(t.Context(), "sw2.example.com")
+	require.Error(t, err)
+}
+
+func TestResolver_Prune(t *testing.T) {
+	dns := &fakeDNS{hosts: map[string][]string{
+		"sw1.example.com": {"10.0.0.1"},
+		"sw2.example.com": {"10.0.0.2"},
+		"sw3.example.com": {"10.0.0.3"},
+	}}
+	r := newResolver(dns, time.Minute)
+
+	for _, host := range []string{"sw1.example.com", "sw2.example.com"} {
+		_, err := r.LookupHost(t.Context(), host)
+		require.NoError(t, err)
+	}
+
+	// sw1 expired long ago and hasn't been asked for since, while sw2 has
+	// expired but was just used, so it's kept for the stale fallback
+	past := time.Now().Add(-2 * dnsIdleTimeout)
+	r.cache[dnsQuery{typ: dnsTypeHost, name: "sw1.example.com"}].expires = past
+	r.cache[dnsQuery{typ: dnsTypeHost, name: "sw1.example.com"}].used = past
+	r.cache[dnsQuery{typ: dnsTypeHost, name: "sw2.example.com"}].expires = past
+
+	_, err := r.LookupHost(t.Context(), "sw3.example.com")
+	require.NoError(t, err)
+
+	assert.Len(t, r.cache, 2)
+	assert.NotContains(t, r.cache, dnsQuery{typ: dnsTypeHost, name: "sw1.example.com"})
+}
+
+func TestResolver_LookupSRV(t *testing.T) {
+	dns := &fakeDNS{srv: map[string][]*net.SRV{
+		"_snmp._udp.example.com": {
+			{Target: "backup.example.com.", Port: 161, Priority: 20},
+			{Target: "sw1.example.com.", Port: 1161, Priority: 10},
+			{Target: "sw2.example.com.", Port: 161, Priority: 10},
+		},
+	}}
+	r := newResolver(dns, time.Hour)
+
+	addrs, err := r.LookupSRV(t.Context(), "_snmp._udp.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sw1.example.com:1161", "sw2.example.com:161", "backup.example.com:161"}, addrs)
+	assert.InDelta(t, 1, testutil.ToFloat64(r.lookups.WithLabelValues(dnsTypeSRV)), 0)
+}
+
+func TestConfig_ResolveTargets(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+targets:
+  - name: sw1.example.com
+    address: 10.0.0.1
+target_groups:
+  - srv: _snmp._udp.example.com
+    auth: v2
+    groups: [ams1]
+    labels:
+      site: ams1
+  - a: edge.example.com
+    port: 1161
+    groups: [edge, ams1]
+  - a: missing.example.com
+auths:
+  v2:
+    community: public
+`))
+	require.NoError(t, err)
+
+	r := newResolver(&fakeDNS{
+		hosts: map[string][]string{"edge.example.com": {"10.0.1.1", "10.0.1.2"}},
+		srv: map[string][]*net.SRV{"_snmp._udp.example.com": {
+			{Target: "sw1.example.com.", Port: 161},
+			{Target: "sw2.example.com.", Port: 1161},
+		}},
+	}, time.Hour)
+
+	targets := cfg.ResolveTargets(t.Context(), r)
+
+	// sw1.example.com is already a static target, and the group which
+	// can't be looked up is skipped
+	require.Len(t, targets, 4)
+	assert.Equal(t, "10.0.0.1", targets[0].Address)
+	assert.Equal(t, TargetConfig{
+		Name: "sw2.example.com", Address: "sw2.example.com:1161",
+		Auth: "v2", Groups: []string{"ams1"}, Labels: map[string]string{"site": "ams1"},
+	}, targets[1])
+	assert.Equal(t, TargetConfig{Name: "10.0.1.1", Address: "10.0.1.1", Port: 1161, Groups: []string{"edge", "ams1"}}, targets[2])
+	assert.Equal(t, "10.0.1.2", targets[3].Name)
+
+	tc, ok := cfg.LookupTarget(t.Context(), r, "sw2.example.com")
+	require.True(t, ok)
+	assert.Equal(t, "v2", tc.Auth)
+
+	_, ok = cfg.LookupTarget(t.Context(), r, "sw3.example.com")
+	assert.False(t, ok)
+
+	members := cfg.GroupTargets(t.Context(), r, "ams1")
+	require.Len(t, members, 3)
+	assert.Equal(t, "sw2.example.com", members[0].Name)
+
+	assert.Len(t, cfg.GroupTargets(t.Context(), r, "edge"), 2)
+	assert.Empty(t, cfg.GroupTargets(t.Context(), r, "nope"))
+
+	var nilCfg *Config
+	assert.Empty(t, nilCfg.ResolveTargets(t.Context(), r))
+}
+
+func TestGetDDMMetrics_Hostname(t *testing.T) {
+	t.Parallel()
+
+	agent := newTestAgent(t, "public", testDDMVars())
+	dns := &fakeDNS{hosts: map[string][]string{"sw1.example.com": {"127.0.0.1"}}}
+	r := newResolver(dns, time.Hour)
+
+	tc := TargetConfig{Address: fmt.Sprintf("sw1.example.com:%d", agent.port)}
+
+	client, err := tc.NewClient(Auth{Version: 2, Community: "public"}, WithResolver(r))
+	require.NoError(t, err)
+
+	result, err := client.GetDDMMetrics(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "agent-switch", result.SysName)
+	assert.Equal(t, 1, dns.count())
+
+	// a hostname which doesn't resolve fails to connect
+	client = NewSNMPClientWithAuth("sw2.example.com", Auth{Version: 2, Community: "public"}, WithResolver(r))
+
+	_, err = client.GetDDMMetrics(t.Context())
+	require.ErrorIs(t, err, ErrConnect)
+}
@@ -0,0 +1,381 @@
+package tplinkddm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSDRefreshInterval is how often the files are re-read regardless of
+// change notifications, which some filesystems (e.g. NFS) never deliver
+const fileSDRefreshInterval = 5 * time.Minute
+
+// fileSDAuthLabel is the file_sd label selecting a target's auth, matching
+// what /sd serves
+const fileSDAuthLabel = "__param_auth"
+
+// FileSDConfig lists Prometheus file_sd files (JSON or YAML, by extension)
+// to discover targets from. Files are glob patterns. Each target in the files
+// becomes a target named by, and with the address of, the target string,
+// with the config's settings, the file's labels added to its own, and the
+// auth given by the __param_auth label if any. Address must be empty.
+type FileSDConfig struct {
+	Files        []string `yaml:"files"`
+	TargetConfig `yaml:",inline"`
+}
+
+// FileDiscovery discovers targets from the file_sd_configs of the current
+// configuration, re-reading the files when they change. A file which fails
+// to load keeps the targets it last loaded successfully.
+type FileDiscovery struct {
+	targets atomic.Pointer[[]TargetConfig]
+
+	// watcher is created once there are files to watch, and signals
+	// watching so that Run starts following it. If it can't be created,
+	// the files are only re-read periodically, and creating it is retried.
+	watcher  *fsnotify.Watcher
+	watching chan struct{}
+
+	// the current configuration's file_sd_configs and auths, the targets
+	// last loaded from each file, and the directories which couldn't be
+	// watched, which are retried on every load
+	configs   []FileSDConfig
+	auths     map[string]Auth
+	files     map[string][]TargetConfig
+	unwatched map[string]bool
+	mu        sync.Mutex
+
+	fileTargets    *prometheus.GaugeVec
+	loadSuccessful *prometheus.GaugeVec
+}
+
+// NewFileDiscovery creates a FileDiscovery with no files. Run must be called
+// to follow changes to the files.
+func NewFileDiscovery() *FileDiscovery {
+	return &FileDiscovery{
+		watching:  make(chan struct{}, 1),
+		files:     map[string][]TargetConfig{},
+		unwatched: map[string]bool{},
+		fileTargets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_file_sd_targets",
+			Help: "Number of targets discovered from each file_sd file",
+		}, []string{"file"}),
+		loadSuccessful: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_file_sd_last_load_successful",
+			Help: "Whether the last load of each file_sd file was successful (1 = success, 0 = failure)",
+		}, []string{"file"}),
+	}
+}
+
+// Targets returns the discovered targets
+func (d *FileDiscovery) Targets() []TargetConfig {
+	if d == nil {
+		return nil
+	}
+
+	if targets := d.targets.Load(); targets != nil {
+		return *targets
+	}
+
+	return nil
+}
+
+// Run re-reads the files whenever something changes in their directories,
+// and every few minutes, until ctx is done. The periodic refresh also picks
+// up new directories matching a pattern, and retries those which couldn't be
+// watched.
+func (d *FileDiscovery) Run(ctx context.Context) {
+	defer d.close()
+
+	ticker := time.NewTicker(fileSDRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		events, errs := d.watcherChannels()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.watching:
+			// the watcher was just created, so select on its channels
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			slog.DebugContext(ctx, "file_sd directory changed", "file", event.Name, "op", event.Op.String())
+			d.load()
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+
+			slog.WarnContext(ctx, "file_sd watcher error", "error", err)
+		case <-ticker.C:
+			d.load()
+		}
+	}
+}
+
+// watcherChannels returns the watcher's channels, or nil channels, which
+// never deliver, if there's no watcher yet
+func (d *FileDiscovery) watcherChannels() (chan fsnotify.Event, chan error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.watcher == nil {
+		return nil, nil
+	}
+
+	return d.watcher.Events, d.watcher.Errors
+}
+
+// close closes the watcher, if any
+func (d *FileDiscovery) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.watcher != nil {
+		_ = d.watcher.Close()
+	}
+}
+
+// update switches to cfg's file_sd_configs and loads their files
+func (d *FileDiscovery) update(cfg *Config) {
+	d.mu.Lock()
+	d.configs = cfg.FileSD
+	d.auths = cfg.Auths
+	d.mu.Unlock()
+
+	d.load()
+}
+
+// watch watches the directories of the current file patterns, expanding
+// any globs in them, and stops watching the directories no longer needed.
+// Files are watched through their directories, so that files which are
+// created, or replaced by renaming, are noticed. d.mu must be held.
+func (d *FileDiscovery) watch() {
+	dirs := map[string]bool{}
+
+	for _, c := range d.configs {
+		for _, pattern := range c.Files {
+			dir := filepath.Dir(pattern)
+			if !strings.ContainsAny(dir, `*?[\`) {
+				dirs[dir] = true
+
+				continue
+			}
+
+			// patterns are checked when the config is loaded
+			matches, _ := filepath.Glob(dir)
+			for _, m := range matches {
+				if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+					dirs[m] = true
+				}
+			}
+		}
+	}
+
+	// without a watcher, adding each directory fails with the watcher's
+	// error, so they're all retried on the next load
+	var err error
+
+	if d.watcher == nil && len(dirs) > 0 {
+		var w *fsnotify.Watcher
+		if w, err = fsnotify.NewWatcher(); err == nil {
+			d.watcher = w
+			d.watching <- struct{}{}
+		}
+	}
+
+	watched := map[string]bool{}
+
+	if d.watcher != nil {
+		for _, dir := range d.watcher.WatchList() {
+			watched[dir] = true
+
+			if !dirs[dir] {
+				_ = d.watcher.Remove(dir)
+			}
+		}
+	}
+
+	unwatched := map[string]bool{}
+
+	for dir := range dirs {
+		if watched[dir] {
+			continue
+		}
+
+		if d.watcher != nil {
+			err = d.watcher.Add(dir)
+		}
+
+		if err != nil {
+			if !d.unwatched[dir] {
+				slog.Warn("can't watch file_sd directory, relying on periodic refresh", "dir", dir, "error", err)
+			}
+
+			unwatched[dir] = true
+		}
+	}
+
+	d.unwatched = unwatched
+}
+
+// load re-reads all of the files, first watching any new directories
+func (d *FileDiscovery) load() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.watch()
+
+	d.fileTargets.Reset()
+	d.loadSuccessful.Reset()
+
+	files := map[string][]TargetConfig{}
+	seen := map[string]bool{}
+
+	var targets []TargetConfig
+
+	for _, c := range d.configs {
+		for _, pattern := range c.Files {
+			// patterns are checked when the config is loaded
+			matches, _ := filepath.Glob(pattern)
+
+			for _, file := range matches {
+				if _, ok := files[file]; ok {
+					continue
+				}
+
+				loaded, err := readFileSD(file, c.TargetConfig, d.auths)
+				if err != nil {
+					slog.Error("failed to load file_sd file, keeping its previous targets", "file", file, "error", err)
+
+					loaded = d.files[file]
+				}
+
+				files[file] = loaded
+
+				d.loadSuccessful.WithLabelValues(file).Set(boolToFloat(err == nil))
+				d.fileTargets.WithLabelValues(file).Set(float64(len(loaded)))
+
+				for _, t := range loaded {
+					if !seen[t.Name] {
+						seen[t.Name] = true
+
+						targets = append(targets, t)
+					}
+				}
+			}
+		}
+	}
+
+	d.files = files
+	d.targets.Store(&targets)
+}
+
+// readFileSD reads the targets from a file_sd file, giving each the settings
+// in defaults
+func readFileSD(file string, defaults TargetConfig, auths map[string]Auth) ([]TargetConfig, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read file_sd file: %w", err)
+	}
+
+	var groups []SDTargetGroup
+
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(b, &groups)
+	} else {
+		err = yaml.Unmarshal(b, &groups)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parse file_sd file %s: %w", file, err)
+	}
+
+	var targets []TargetConfig
+
+	for _, g := range groups {
+		t := defaults
+		t.Labels = maps.Clone(defaults.Labels)
+
+		for k, v := range g.Labels {
+			switch {
+			case k == fileSDAuthLabel:
+				t.Auth = v
+			case strings.HasPrefix(k, "__"):
+				// other Prometheus-internal labels don't apply here
+			case reservedLabels[k]:
+				return nil, fmt.Errorf("file_sd file %s: label %q is reserved", file, k)
+			default:
+				if t.Labels == nil {
+					t.Labels = map[string]string{}
+				}
+
+				t.Labels[k] = v
+			}
+		}
+
+		if _, ok := auths[t.Auth]; t.Auth != "" && !ok {
+			return nil, fmt.Errorf("file_sd file %s: unknown auth %q", file, t.Auth)
+		}
+
+		for _, target := range g.Targets {
+			addr, err := ParseAddress(target)
+			if err != nil {
+				return nil, fmt.Errorf("file_sd file %s: %w", file, err)
+			}
+
+			if addr.Port != 0 && t.Port != 0 {
+				return nil, fmt.Errorf("file_sd file %s: target %q: port given in both address and port", file, target)
+			}
+
+			tc := t
+			tc.Name, tc.Address = target, target
+			targets = append(targets, tc)
+		}
+	}
+
+	return targets, nil
+}
+
+// validFileSDPattern reports whether pattern is a valid glob for file_sd
+// files, which must be JSON or YAML
+func validFileSDPattern(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("file_sd pattern %q: %w", pattern, err)
+	}
+
+	if !slices.Contains([]string{".json", ".yml", ".yaml"}, strings.ToLower(filepath.Ext(pattern))) {
+		return fmt.Errorf("file_sd pattern %q must end in .json, .yml or .yaml", pattern)
+	}
+
+	return nil
+}
+
+// Describe implements prometheus.Collector
+func (d *FileDiscovery) Describe(ch chan<- *prometheus.Desc) {
+	d.fileTargets.Describe(ch)
+	d.loadSuccessful.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (d *FileDiscovery) Collect(ch chan<- prometheus.Metric) {
+	d.fileTargets.Collect(ch)
+	d.loadSuccessful.Collect(ch)
+}
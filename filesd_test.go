@@ -0,0 +1,204 @@
+package tplinkddm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func targetNames(targets []TargetConfig) []string {
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.Name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+func TestReadFileSD(t *testing.T) {
+	dir := t.TempDir()
+	auths := map[string]Auth{"v3": {Version: 3}}
+	defaults := TargetConfig{Groups: []string{"cmdb"}, Labels: map[string]string{"source": "cmdb"}}
+
+	jsonFile := filepath.Join(dir, "switches.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`[
+  {"targets": ["10.0.0.1", "sw2.example.com:1161"], "labels": {"site": "ams1", "__param_auth": "v3", "__meta_x": "y"}},
+  {"targets": ["10.0.0.3"]}
+]`), 0o600))
+
+	targets, err := readFileSD(jsonFile, defaults, auths)
+	require.NoError(t, err)
+	require.Len(t, targets, 3)
+	assert.Equal(t, TargetConfig{
+		Name: "10.0.0.1", Address: "10.0.0.1", Auth: "v3", Groups: []string{"cmdb"},
+		Labels: map[string]string{"source": "cmdb", "site": "ams1"},
+	}, targets[0])
+	assert.Equal(t, "sw2.example.com:1161", targets[1].Address)
+	assert.Equal(t, TargetConfig{
+		Name: "10.0.0.3", Address: "10.0.0.3", Groups: []string{"cmdb"},
+		Labels: map[string]string{"source": "cmdb"},
+	}, targets[2])
+
+	// the defaults aren't modified
+	assert.Equal(t, map[string]string{"source": "cmdb"}, defaults.Labels)
+
+	yamlFile := filepath.Join(dir, "switches.yml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte("- targets: [10.0.1.1]\n  labels:\n    rack: r1\n"), 0o600))
+
+	targets, err = readFileSD(yamlFile, TargetConfig{}, auths)
+	require.NoError(t, err)
+	assert.Equal(t, []TargetConfig{{Name: "10.0.1.1", Address: "10.0.1.1", Labels: map[string]string{"rack": "r1"}}}, targets)
+
+	for name, content := range map[string]string{
+		"syntax.json":   `[{"targets": [`,
+		"auth.json":     `[{"targets": ["10.0.0.1"], "labels": {"__param_auth": "nope"}}]`,
+		"reserved.json": `[{"targets": ["10.0.0.1"], "labels": {"port": "1"}}]`,
+		"address.json":  `[{"targets": ["ftp://10.0.0.1"]}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+
+			_, err := readFileSD(file, TargetConfig{}, auths)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFileDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	require.NoError(t, os.WriteFile(a, []byte(`[{"targets": ["10.0.0.1", "10.0.0.2"]}]`), 0o600))
+
+	d := NewFileDiscovery()
+
+	go d.Run(t.Context())
+
+	d.update(&Config{FileSD: []FileSDConfig{{Files: []string{filepath.Join(dir, "*.json")}}}})
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, targetNames(d.Targets()))
+	assert.InDelta(t, 2, testutil.ToFloat64(d.fileTargets.WithLabelValues(a)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(d.loadSuccessful.WithLabelValues(a)), 0)
+
+	// a new file is picked up
+	b := filepath.Join(dir, "b.json")
+	require.NoError(t, os.WriteFile(b, []byte(`[{"targets": ["10.0.0.2", "10.0.0.3"]}]`), 0o600))
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, targetNames(d.Targets()))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a broken file keeps its previous targets
+	require.NoError(t, os.WriteFile(a, []byte(`[{"targets": [`), 0o600))
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(d.loadSuccessful.WithLabelValues(a)) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, targetNames(d.Targets()))
+
+	// a removed file's targets go
+	require.NoError(t, os.Remove(b))
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"10.0.0.1", "10.0.0.2"}, targetNames(d.Targets()))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, (*FileDiscovery)(nil).Targets())
+}
+
+func TestFileDiscovery_Dirs(t *testing.T) {
+	dir := t.TempDir()
+	site := filepath.Join(dir, "ams1")
+	later := filepath.Join(dir, "later")
+	require.NoError(t, os.Mkdir(site, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(site, "a.json"), []byte(`[{"targets": ["10.0.0.1"]}]`), 0o600))
+
+	d := NewFileDiscovery()
+
+	go d.Run(t.Context())
+
+	// globs in the directory are expanded, and a directory which doesn't
+	// exist yet isn't taken as watched
+	d.update(&Config{FileSD: []FileSDConfig{{Files: []string{
+		filepath.Join(dir, "*", "*.json"),
+		filepath.Join(later, "*.yml"),
+	}}}})
+	assert.Equal(t, []string{"10.0.0.1"}, targetNames(d.Targets()))
+	assert.ElementsMatch(t, []string{site}, d.watcher.WatchList())
+	d.mu.Lock()
+	assert.Equal(t, map[string]bool{later: true}, d.unwatched)
+	d.mu.Unlock()
+
+	// it's watched once it exists, on the next load
+	require.NoError(t, os.Mkdir(later, 0o700))
+	d.load()
+	assert.ElementsMatch(t, []string{site, later}, d.watcher.WatchList())
+	d.mu.Lock()
+	assert.Empty(t, d.unwatched)
+	d.mu.Unlock()
+
+	require.NoError(t, os.WriteFile(filepath.Join(later, "b.yml"), []byte("- targets: [10.0.0.2]\n"), 0o600))
+
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"10.0.0.1", "10.0.0.2"}, targetNames(d.Targets()))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// directories no longer needed aren't watched
+	d.update(&Config{FileSD: []FileSDConfig{{Files: []string{filepath.Join(later, "*.yml")}}}})
+	assert.Equal(t, []string{later}, d.watcher.WatchList())
+}
+
+func TestConfigReloader_FileDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("targets:\n  - address: 10.0.0.1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdb.yml"), []byte("- targets: [10.0.0.1, 10.0.0.9]\n"), 0o600))
+
+	r, err := NewConfigReloader(path)
+	require.NoError(t, err)
+
+	d := NewFileDiscovery()
+
+	r.SetFileDiscovery(d)
+	assert.Equal(t, []string{"10.0.0.1"}, targetNames(r.Config().ResolveTargets(t.Context(), nil)))
+
+	// there's nothing to watch, so no watcher is created
+	d.mu.Lock()
+	assert.Nil(t, d.watcher)
+	d.mu.Unlock()
+
+	// a reload brings in the file_sd_configs, and static targets win over
+	// discovered ones with the same name
+	require.NoError(t, os.WriteFile(path, []byte(`
+auths:
+  v3:
+    version: 3
+    username: monitor
+    security_level: noAuthNoPriv
+targets:
+  - address: 10.0.0.1
+file_sd_configs:
+  - files: [`+filepath.Join(dir, "*.yml")+`]
+    auth: v3
+    groups: [cmdb]
+`), 0o600))
+	require.NoError(t, r.Reload())
+
+	cfg := r.Config()
+	targets := cfg.ResolveTargets(t.Context(), nil)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.9"}, targetNames(targets))
+	assert.Empty(t, targets[0].Auth)
+
+	tc, ok := cfg.LookupTarget(t.Context(), nil, "10.0.0.9")
+	require.True(t, ok)
+	assert.Equal(t, "v3", tc.Auth)
+	assert.Len(t, cfg.GroupTargets(t.Context(), nil, "cmdb"), 1)
+	assert.Len(t, cfg.ServiceDiscovery(t.Context(), nil), 2)
+}
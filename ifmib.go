@@ -0,0 +1,100 @@
+package tplinkddm
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// IF-MIB columns, walked alongside the DDM tables so ports can be correlated
+// with interface names, descriptions and link state
+const (
+	oidIfOperStatus = "1.3.6.1.2.1.2.2.1.8"     // ifTable: 1=up, 2=down, 3=testing, ...
+	oidIfName       = "1.3.6.1.2.1.31.1.1.1.1"  // ifXTable
+	oidIfHighSpeed  = "1.3.6.1.2.1.31.1.1.1.15" // ifXTable, in Mbit/s
+	oidIfAlias      = "1.3.6.1.2.1.31.1.1.1.18" // ifXTable
+)
+
+// ifRow holds the raw IF-MIB values for one ifIndex
+type ifRow struct {
+	name       string
+	alias      string
+	operStatus string
+	highSpeed  string
+}
+
+// ifColumns maps each walked IF-MIB column to its field in ifRow
+//
+//nolint:gochecknoglobals // lookup table
+var ifColumns = []tableColumn[ifRow]{
+	{oid: oidIfName, field: func(r *ifRow) *string { return &r.name }},
+	{oid: oidIfAlias, field: func(r *ifRow) *string { return &r.alias }},
+	{oid: oidIfOperStatus, field: func(r *ifRow) *string { return &r.operStatus }},
+	{oid: oidIfHighSpeed, field: func(r *ifRow) *string { return &r.highSpeed }},
+}
+
+// walkIfMIB walks the IF-MIB columns into data.ifRows
+func (c *SNMPClient) walkIfMIB(ctx context.Context, client *gosnmp.GoSNMP, data *ddmWalkData) {
+	ctx, span := tracer.Start(ctx, "SNMPClient.walkIfMIB")
+	defer span.End()
+
+	client.Context = ctx
+
+	rows, err := walkColumns(client, ifColumns, &data.pduCount)
+	if err != nil {
+		slog.DebugContext(ctx, "IF-MIB walk failed", "target", c.target, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "IF-MIB walk failed")
+	}
+
+	data.ifRows = rows
+
+	span.SetAttributes(attribute.Int("snmp.if_rows", len(data.ifRows)))
+}
+
+// matchIfIndex returns the ifIndex for a DDM row. TP-Link DDM tables are
+// indexed by ifIndex, so the row index is tried first; failing that, the
+// interface whose ifName ends in the port's "unit/slot/port" name is used.
+func matchIfIndex(ifRows map[string]*ifRow, index, iface string) (string, bool) {
+	if _, ok := ifRows[index]; ok {
+		return index, true
+	}
+
+	for _, ifIndex := range sortedKeys(ifRows) {
+		if ifNameSuffix(ifRows[ifIndex].name) == iface {
+			return ifIndex, true
+		}
+	}
+
+	return "", false
+}
+
+// ifNameSuffix returns the trailing port number part of an ifName, e.g.
+// "1/0/5" from "gigabitEthernet 1/0/5" or "Gi1/0/5"
+func ifNameSuffix(name string) string {
+	name = strings.TrimSpace(name)
+
+	i := strings.LastIndexFunc(name, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '/'
+	})
+
+	return name[i+1:]
+}
+
+// applyIfRow copies the IF-MIB values onto m. Values which are missing or
+// fail to parse are left as zero.
+func applyIfRow(m *DDMMetrics, ifIndex string, r *ifRow) {
+	m.IfIndex = ifIndex
+	m.IfName = r.name
+	m.IfAlias = r.alias
+	m.OperStatus, _ = strconv.Atoi(r.operStatus)
+
+	if mbps, err := parseFloat(r.highSpeed); err == nil {
+		m.Speed = mbps * 1e6
+	}
+}
@@ -0,0 +1,99 @@
+package tplinkddm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIfNameSuffix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"gigabitEthernet 1/0/5", "1/0/5"},
+		{"Gi1/0/5", "1/0/5"},
+		{"ten-gigabitEthernet 2/0/26 ", "2/0/26"},
+		{"1/0/5", "1/0/5"},
+		{"port 7", "7"},
+		{"Vlan1", "1"},
+		{"loopback", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ifNameSuffix(tt.name), tt.name)
+	}
+}
+
+func TestMatchIfIndex(t *testing.T) {
+	t.Parallel()
+
+	ifRows := map[string]*ifRow{
+		"49153": {name: "gigabitEthernet 1/0/1"},
+		"49154": {name: "gigabitEthernet 1/0/2"},
+		"50001": {name: "gigabitEthernet 2/0/1"},
+	}
+
+	got, ok := matchIfIndex(ifRows, "49154", "1/0/2")
+	assert.True(t, ok)
+	assert.Equal(t, "49154", got)
+
+	// DDM index isn't an ifIndex: fall back to ifName
+	got, ok = matchIfIndex(ifRows, "2", "2/0/1")
+	assert.True(t, ok)
+	assert.Equal(t, "50001", got)
+
+	_, ok = matchIfIndex(ifRows, "3", "1/0/3")
+	assert.False(t, ok)
+
+	_, ok = matchIfIndex(nil, "49153", "1/0/1")
+	assert.False(t, ok)
+}
+
+func TestGetDDMMetrics_IfMIB(t *testing.T) {
+	t.Parallel()
+
+	str := func(oid, idx, val string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: "." + oid + "." + idx, Type: gosnmp.OctetString, Value: []byte(val)}
+	}
+
+	vars := append(testDDMVars(),
+		str(oidIfName, "49153", "gigabitEthernet 1/0/1"),
+		str(oidIfAlias, "49153", "uplink to core-2"),
+		gosnmp.SnmpPDU{Name: "." + oidIfOperStatus + ".49153", Type: gosnmp.Integer, Value: 1},
+		gosnmp.SnmpPDU{Name: "." + oidIfHighSpeed + ".49153", Type: gosnmp.Gauge32, Value: uint(1000)},
+		// port 1/0/2 isn't at ifIndex 49154 on this switch
+		str(oidIfName, "7", "gigabitEthernet 1/0/2"),
+		gosnmp.SnmpPDU{Name: "." + oidIfOperStatus + ".7", Type: gosnmp.Integer, Value: 2},
+	)
+
+	agent := newTestAgent(t, "public", vars)
+
+	client := NewSNMPClient("127.0.0.1", "public")
+	client.port = agent.port
+
+	result, err := client.GetDDMMetrics(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.Metrics, 2)
+
+	assert.Equal(t, 16, result.PDUs)
+
+	p1 := result.Metrics[0]
+	assert.Equal(t, "49153", p1.IfIndex)
+	assert.Equal(t, "gigabitEthernet 1/0/1", p1.IfName)
+	assert.Equal(t, "uplink to core-2", p1.IfAlias)
+	assert.Equal(t, 1, p1.OperStatus)
+	assert.InDelta(t, 1e9, p1.Speed, 0)
+
+	p2 := result.Metrics[1]
+	assert.Equal(t, "7", p2.IfIndex)
+	assert.Equal(t, "gigabitEthernet 1/0/2", p2.IfName)
+	assert.Equal(t, 2, p2.OperStatus)
+	assert.Zero(t, p2.Speed)
+}
@@ -0,0 +1,174 @@
+package tplinkddm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Limiter bounds the number of SNMP walks running at once, both overall and
+// per switch, so that many scrapes arriving together (e.g. after a
+// Prometheus restart) queue up instead of flooding switches which drop UDP
+// under load. Queued walks give up when their context is done.
+type Limiter struct {
+	global   chan struct{} // nil if unlimited
+	switches map[string]*switchSlots
+	inFlight prometheus.Gauge
+	queued   prometheus.Gauge
+
+	perSwitch int
+	mu        sync.Mutex
+}
+
+// switchSlots is the per-switch semaphore, shared by the walks using it
+type switchSlots struct {
+	slots chan struct{}
+	users int
+}
+
+// NewLimiter creates a Limiter allowing up to maxWalks walks at once, and up
+// to perSwitch walks of any one switch. 0 means no limit.
+func NewLimiter(maxWalks, perSwitch int) *Limiter {
+	l := &Limiter{
+		switches: map[string]*switchSlots{},
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_walks_in_flight",
+			Help: "Number of SNMP walks currently running",
+		}),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_walks_queued",
+			Help: "Number of SNMP walks waiting for a concurrency slot",
+		}),
+		perSwitch: perSwitch,
+	}
+
+	if maxWalks > 0 {
+		l.global = make(chan struct{}, maxWalks)
+	}
+
+	return l
+}
+
+// Getter wraps client so its walks count against the limits for the switch
+// at address
+func (l *Limiter) Getter(address string, client SNMPGetter) SNMPGetter {
+	return &limitedGetter{limiter: l, client: client, address: address}
+}
+
+type limitedGetter struct {
+	limiter *Limiter
+	client  SNMPGetter
+	address string
+}
+
+// GetDDMMetrics implements SNMPGetter, waiting for a free slot first
+func (g *limitedGetter) GetDDMMetrics(ctx context.Context) (*DDMResult, error) {
+	release, err := g.limiter.acquire(ctx, g.address)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	return g.client.GetDDMMetrics(ctx)
+}
+
+// acquire waits for a slot for the switch and then a global slot, and
+// returns a function releasing both. The switch's slot is taken first, so
+// walks queued behind another walk of the same switch don't hold global
+// slots while they wait.
+func (l *Limiter) acquire(ctx context.Context, address string) (func(), error) {
+	l.queued.Inc()
+	defer l.queued.Dec()
+
+	sw := l.switchSlots(address)
+
+	if err := acquireSlot(ctx, sw.slots); err != nil {
+		l.releaseSwitch(address, sw)
+
+		return nil, err
+	}
+
+	if err := acquireSlot(ctx, l.global); err != nil {
+		releaseSlot(sw.slots)
+		l.releaseSwitch(address, sw)
+
+		return nil, err
+	}
+
+	l.inFlight.Inc()
+
+	return func() {
+		l.inFlight.Dec()
+		releaseSlot(l.global)
+		releaseSlot(sw.slots)
+		l.releaseSwitch(address, sw)
+	}, nil
+}
+
+// switchSlots returns the switch's semaphore, creating it if needed
+func (l *Limiter) switchSlots(address string) *switchSlots {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sw, ok := l.switches[address]
+	if !ok {
+		sw = &switchSlots{}
+		if l.perSwitch > 0 {
+			sw.slots = make(chan struct{}, l.perSwitch)
+		}
+
+		l.switches[address] = sw
+	}
+
+	sw.users++
+
+	return sw
+}
+
+// releaseSwitch drops the switch's semaphore once nothing is using it, so
+// one-off targets don't accumulate
+func (l *Limiter) releaseSwitch(address string, sw *switchSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sw.users--
+	if sw.users == 0 {
+		delete(l.switches, address)
+	}
+}
+
+// acquireSlot takes a slot from slots, waiting until one is free or ctx is
+// done. A nil slots is unlimited.
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	if slots == nil {
+		return nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for a free SNMP walk slot: %w", ctx.Err())
+	}
+}
+
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+// Describe implements prometheus.Collector
+func (l *Limiter) Describe(ch chan<- *prometheus.Desc) {
+	l.inFlight.Describe(ch)
+	l.queued.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (l *Limiter) Collect(ch chan<- prometheus.Metric) {
+	l.inFlight.Collect(ch)
+	l.queued.Collect(ch)
+}
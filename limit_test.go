@@ -0,0 +1,86 @@
+package tplinkddm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Global(t *testing.T) {
+	l := NewLimiter(2, 0)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{}}
+
+	var wg sync.WaitGroup
+
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		wg.Go(func() {
+			_, err := l.Getter(addr, client).GetDDMMetrics(t.Context())
+			assert.NoError(t, err)
+		})
+	}
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(l.inFlight) == 2 && testutil.ToFloat64(l.queued) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, 2, client.count())
+
+	close(client.release)
+	wg.Wait()
+
+	assert.Equal(t, 3, client.count())
+	assert.Zero(t, testutil.ToFloat64(l.inFlight))
+	assert.Zero(t, testutil.ToFloat64(l.queued))
+	assert.Empty(t, l.switches)
+}
+
+func TestLimiter_PerSwitch(t *testing.T) {
+	l := NewLimiter(0, 1)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{}}
+
+	var wg sync.WaitGroup
+
+	for _, addr := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2"} {
+		wg.Go(func() {
+			_, err := l.Getter(addr, client).GetDDMMetrics(t.Context())
+			assert.NoError(t, err)
+		})
+	}
+
+	// the second walk of 10.0.0.1 waits, the other switch doesn't
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(l.inFlight) == 2 && testutil.ToFloat64(l.queued) == 1
+	}, time.Second, time.Millisecond)
+
+	close(client.release)
+	wg.Wait()
+
+	assert.Equal(t, 3, client.count())
+}
+
+func TestLimiter_QueueDeadline(t *testing.T) {
+	l := NewLimiter(1, 0)
+	client := &blockingClient{release: make(chan struct{}), result: &DDMResult{}}
+
+	go func() {
+		_, _ = l.Getter("10.0.0.1", client).GetDDMMetrics(t.Context())
+	}()
+
+	require.Eventually(t, func() bool { return client.count() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := l.Getter("10.0.0.2", client).GetDDMMetrics(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, reasonTimeout, scrapeErrorReason(err))
+	assert.Equal(t, 1, client.count())
+	assert.Zero(t, testutil.ToFloat64(l.queued))
+
+	close(client.release)
+}
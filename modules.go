@@ -0,0 +1,145 @@
+package tplinkddm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ModuleTracker remembers the last transceiver seen in each port, so module
+// swaps can be told apart from a degrading module. State is kept in memory,
+// and optionally persisted to a JSON file so it survives restarts.
+type ModuleTracker struct {
+	// modules holds the last seen module, keyed by target then interface
+	modules map[string]map[string]*ModuleRecord
+	path    string
+	mu      sync.Mutex
+}
+
+// ModuleRecord is the last module seen in a port
+type ModuleRecord struct {
+	Installed  time.Time `json:"installed"`
+	Vendor     string    `json:"vendor"`
+	PartNumber string    `json:"part_number"`
+	Serial     string    `json:"serial"`
+	Changes    int       `json:"changes"`
+}
+
+// NewModuleTracker creates a tracker. If path is non-empty, previous state is
+// loaded from it (a missing file is not an error) and each change is saved
+// back to it.
+func NewModuleTracker(path string) (*ModuleTracker, error) {
+	t := &ModuleTracker{
+		modules: map[string]map[string]*ModuleRecord{},
+		path:    path,
+	}
+
+	if path == "" {
+		return t, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read module state: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &t.modules); err != nil {
+		return nil, fmt.Errorf("parse module state %s: %w", path, err)
+	}
+
+	if t.modules == nil {
+		t.modules = map[string]map[string]*ModuleRecord{}
+	}
+
+	return t, nil
+}
+
+// Observe records the module currently in a port, and returns the port's
+// record. A module is identified by vendor, part number and serial; a
+// different identity from last time counts as a change. The first module
+// seen in a port is recorded as installed now, with no changes. Ports
+// without identifying information (e.g. empty cages) are not recorded, so
+// removing and reinserting the same module is not a change.
+func (t *ModuleTracker) Observe(target string, m DDMMetrics, now time.Time) (ModuleRecord, bool) {
+	if m.Vendor == "" && m.PartNumber == "" && m.Serial == "" {
+		return ModuleRecord{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ports, ok := t.modules[target]
+	if !ok {
+		ports = map[string]*ModuleRecord{}
+		t.modules[target] = ports
+	}
+
+	rec, ok := ports[m.Interface]
+
+	switch {
+	case !ok:
+		rec = &ModuleRecord{Installed: now, Vendor: m.Vendor, PartNumber: m.PartNumber, Serial: m.Serial}
+		ports[m.Interface] = rec
+	case rec.Vendor != m.Vendor || rec.PartNumber != m.PartNumber || rec.Serial != m.Serial:
+		slog.Info("SFP module changed", "target", target, "interface", m.Interface,
+			"old_serial", rec.Serial, "new_serial", m.Serial)
+
+		rec.Installed = now
+		rec.Vendor, rec.PartNumber, rec.Serial = m.Vendor, m.PartNumber, m.Serial
+		rec.Changes++
+	default:
+		return *rec, true
+	}
+
+	if err := t.save(); err != nil {
+		slog.Warn("failed to save SFP module state", "path", t.path, "error", err)
+	}
+
+	return *rec, true
+}
+
+// save writes the state file, if any. Must be called with t.mu held.
+func (t *ModuleTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(t.modules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal module state: %w", err)
+	}
+
+	// write to a temp file and rename, so a crash can't leave a truncated file
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), filepath.Base(t.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("write module state: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close module state: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("rename module state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package tplinkddm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleTracker_Observe(t *testing.T) {
+	tracker, err := NewModuleTracker("")
+	require.NoError(t, err)
+
+	t0 := time.Unix(1700000000, 0)
+	m := DDMMetrics{Interface: "1/0/25", Vendor: "FS", PartNumber: "SFP-10GLR-31", Serial: "A1"}
+
+	rec, ok := tracker.Observe("sw1", m, t0)
+	require.True(t, ok)
+	assert.Equal(t, t0, rec.Installed)
+	assert.Zero(t, rec.Changes)
+
+	// same module later: no change
+	rec, _ = tracker.Observe("sw1", m, t0.Add(time.Hour))
+	assert.Equal(t, t0, rec.Installed)
+	assert.Zero(t, rec.Changes)
+
+	// empty cage: not recorded, and the old module is remembered
+	_, ok = tracker.Observe("sw1", DDMMetrics{Interface: "1/0/25"}, t0.Add(2*time.Hour))
+	assert.False(t, ok)
+
+	rec, _ = tracker.Observe("sw1", m, t0.Add(3*time.Hour))
+	assert.Zero(t, rec.Changes)
+
+	// a different module is a change
+	m.Serial = "B2"
+	t1 := t0.Add(4 * time.Hour)
+
+	rec, _ = tracker.Observe("sw1", m, t1)
+	assert.Equal(t, t1, rec.Installed)
+	assert.Equal(t, 1, rec.Changes)
+
+	// the same port on another target is tracked separately
+	rec, _ = tracker.Observe("sw2", m, t1)
+	assert.Zero(t, rec.Changes)
+}
+
+func TestModuleTracker_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modules.json")
+
+	tracker, err := NewModuleTracker(path)
+	require.NoError(t, err)
+
+	t0 := time.Unix(1700000000, 0).UTC()
+	m := DDMMetrics{Interface: "1/0/25", Vendor: "FS", Serial: "A1"}
+
+	tracker.Observe("sw1", m, t0)
+	m.Serial = "B2"
+	tracker.Observe("sw1", m, t0.Add(time.Hour))
+
+	// a new tracker picks up where the old one left off
+	tracker, err = NewModuleTracker(path)
+	require.NoError(t, err)
+
+	rec, ok := tracker.Observe("sw1", m, t0.Add(2*time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, 1, rec.Changes)
+	assert.True(t, t0.Add(time.Hour).Equal(rec.Installed))
+}
+
+func TestNewModuleTracker_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	// a missing file is fine
+	_, err := NewModuleTracker(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+	_, err = NewModuleTracker(path)
+	assert.Error(t, err)
+}
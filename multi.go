@@ -0,0 +1,36 @@
+package tplinkddm
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MultiCollector merges several targets' collectors into one exposition,
+// collecting them concurrently. Each target's metrics carry its target label,
+// so they don't collide, and a target which fails to scrape only reports its
+// own tplink_ddm_up as 0.
+type MultiCollector struct {
+	collectors []prometheus.Collector
+}
+
+// NewMultiCollector creates a MultiCollector for the given collectors
+func NewMultiCollector(collectors ...prometheus.Collector) *MultiCollector {
+	return &MultiCollector{collectors: collectors}
+}
+
+// Describe implements prometheus.Collector. It describes nothing, making the
+// MultiCollector unchecked: the targets' collectors describe the same
+// metrics, which a registry would otherwise reject as duplicates.
+func (m *MultiCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector
+func (m *MultiCollector) Collect(ch chan<- prometheus.Metric) {
+	var wg sync.WaitGroup
+
+	for _, c := range m.collectors {
+		wg.Go(func() { c.Collect(ch) })
+	}
+
+	wg.Wait()
+}
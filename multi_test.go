@@ -0,0 +1,56 @@
+package tplinkddm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiCollector(t *testing.T) {
+	ok := &mockSNMPClient{result: &DDMResult{
+		SysName: "sw1",
+		Metrics: []DDMMetrics{{Port: "25", Interface: "1/0/25", Temperature: 40}},
+	}}
+	dead := &mockSNMPClient{err: errors.New("no route to host")}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewMultiCollector(
+		prometheus.WrapCollectorWith(prometheus.Labels{"site": "ams1"}, NewCollector(ok, "10.0.0.1")),
+		NewCollector(dead, "10.0.0.2"),
+	))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	up := map[string]float64{}
+	temps := 0
+
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+
+			switch mf.GetName() {
+			case "tplink_ddm_up":
+				up[labels["target"]] = m.GetGauge().GetValue()
+
+				if labels["target"] == "10.0.0.1" {
+					assert.Equal(t, "ams1", labels["site"])
+				}
+			case "tplink_sfp_temperature_celsius":
+				temps++
+
+				assert.Equal(t, "10.0.0.1", labels["target"])
+			}
+		}
+	}
+
+	// the dead switch only fails its own up
+	assert.Equal(t, map[string]float64{"10.0.0.1": 1, "10.0.0.2": 0}, up)
+	assert.Equal(t, 1, temps)
+}
@@ -0,0 +1,53 @@
+package tplinkddm
+
+import (
+	"sync"
+	"time"
+)
+
+// ParseErrorCounts accumulates unparseable DDM readings per target and field
+// across scrapes, so tplink_ddm_parse_errors_total can be a real counter even
+// though each scrape gets a fresh Collector.
+type ParseErrorCounts struct {
+	counts map[string]map[string]int
+	// walked is the time of the last walk counted for each target
+	walked map[string]time.Time
+	mu     sync.Mutex
+}
+
+// NewParseErrorCounts creates an empty ParseErrorCounts
+func NewParseErrorCounts() *ParseErrorCounts {
+	return &ParseErrorCounts{
+		counts: map[string]map[string]int{},
+		walked: map[string]time.Time{},
+	}
+}
+
+// add adds the parse errors from a target's walk at the given time, and
+// returns the target's running totals. A walk that was already counted
+// (the same cached result served to several scrapes) isn't counted again.
+func (p *ParseErrorCounts) add(target string, walked time.Time, errs map[string]int) map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	totals, ok := p.counts[target]
+	if !ok {
+		totals = map[string]int{}
+		p.counts[target] = totals
+	}
+
+	if walked.IsZero() || !walked.Equal(p.walked[target]) {
+		for field, n := range errs {
+			totals[field] += n
+		}
+
+		p.walked[target] = walked
+	}
+
+	out := make(map[string]int, len(totals))
+	for field, n := range totals {
+		out[field] = n
+	}
+
+	return out
+}
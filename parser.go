@@ -3,10 +3,22 @@ package tplinkddm
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
+// dBmToWatts converts optical power from dBm to watts. With no light, some
+// modules report -inf dBm (or a floor like -40 dBm), which converts to 0 W (or
+// close to it), so watts can be summed and averaged where dBm can't.
+func dBmToWatts(dBm float64) float64 {
+	if math.IsInf(dBm, -1) {
+		return 0
+	}
+
+	return math.Pow(10, (dBm-30)/10)
+}
+
 // parseFloat parses a TP-Link DDM DisplayString value to float64
 func parseFloat(s string) (float64, error) {
 	s = strings.TrimSpace(s)
@@ -22,25 +34,111 @@ func parseFloat(s string) (float64, error) {
 	return f, nil
 }
 
-// parsePort normalizes port string (extracts port from "1/0/N" format)
-func parsePort(s string) (string, error) {
+// portName is a port's stack unit, slot and port number, parsed from a
+// TP-Link interface name like "1/0/5". Unit and Slot are empty for plain
+// port numbers.
+type portName struct {
+	Interface string // normalized full name, e.g. "1/0/5"
+	Unit      string
+	Slot      string
+	Port      string
+}
+
+// parseInterface parses a port string in "unit/slot/port" or plain "N" format
+func parseInterface(s string) (portName, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
-		return "", errors.New("empty port")
+		return portName{}, errors.New("empty port")
 	}
 
-	// Handle "1/0/N" format (stack/slot/port)
 	parts := strings.Split(s, "/")
-	if len(parts) == 3 {
-		// Return just the port number
-		return parts[2], nil
+
+	switch len(parts) {
+	case 1, 3:
+	default:
+		return portName{}, fmt.Errorf("invalid port %q: expected unit/slot/port", s)
+	}
+
+	// normalize each component to remove leading zeros
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return portName{}, fmt.Errorf("invalid port: %w", err)
+		}
+
+		parts[i] = strconv.Itoa(n)
+	}
+
+	if len(parts) == 1 {
+		return portName{Interface: parts[0], Port: parts[0]}, nil
+	}
+
+	return portName{
+		Interface: strings.Join(parts, "/"),
+		Unit:      parts[0],
+		Slot:      parts[1],
+		Port:      parts[2],
+	}, nil
+}
+
+// DDM reading field names, used to track which DDMMetrics readings were
+// present, and as the field label of tplink_ddm_parse_errors_total
+const (
+	FieldTemperature = "temperature"
+	FieldVoltage     = "voltage"
+	FieldBiasCurrent = "bias_current"
+	FieldTxPower     = "tx_power"
+	FieldRxPower     = "rx_power"
+)
+
+// thresholdField returns the field name of a threshold, e.g.
+// "temperature_high_alarm"
+func thresholdField(measurement, level, typ string) string {
+	return measurement + "_" + level + "_" + typ
+}
+
+// isPlaceholder reports whether s is one of the values TP-Link firmware
+// reports in place of a reading, e.g. for an empty SFP cage
+func isPlaceholder(s string) bool {
+	switch strings.TrimSpace(s) {
+	case "", "-", "--", "---", "N/A", "n/a", "NA":
+		return true
+	}
+
+	return false
+}
+
+// parseReading parses a raw DDM reading for the given field. Missing values
+// and placeholders mark the field invalid; anything else that isn't a number
+// also records a parse error.
+func (m *DDMMetrics) parseReading(field, s string) float64 {
+	if isPlaceholder(s) {
+		m.setInvalid(field)
+
+		return 0
 	}
 
-	// Fallback: parse as int to remove leading zeros
-	port, err := strconv.Atoi(s)
+	v, err := parseFloat(s)
 	if err != nil {
-		return "", fmt.Errorf("invalid port: %w", err)
+		m.setInvalid(field)
+		m.ParseErrors = append(m.ParseErrors, field)
+
+		return 0
+	}
+
+	return v
+}
+
+func (m *DDMMetrics) setInvalid(field string) {
+	if m.Invalid == nil {
+		m.Invalid = map[string]bool{}
 	}
 
-	return strconv.Itoa(port), nil
+	m.Invalid[field] = true
+}
+
+// Valid reports whether the reading for the given field was present and
+// parsed. Readings that aren't valid are left as zero.
+func (m *DDMMetrics) Valid(field string) bool {
+	return !m.Invalid[field]
 }
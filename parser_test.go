@@ -1,7 +1,10 @@
 package tplinkddm
 
 import (
+	"math"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParseFloat(t *testing.T) {
@@ -22,6 +25,7 @@ func TestParseFloat(t *testing.T) {
 		{"negative", "-10.500000", -10.5, false},
 		{"invalid", "not a number", 0, true},
 		{"empty", "", 0, true},
+		{"no light", "-inf", math.Inf(-1), false},
 	}
 
 	for _, tt := range tests {
@@ -43,39 +47,75 @@ func TestParseFloat(t *testing.T) {
 	}
 }
 
-func TestParsePort(t *testing.T) {
+func TestParseInterface(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name    string
 		input   string
-		want    string
+		want    portName
 		wantErr bool
 	}{
-		{"single digit", "1", "1", false},
-		{"double digit", "16", "16", false},
-		{"with leading zero", "01", "1", false},
-		{"tplink format", "1/0/2", "2", false},
-		{"tplink format double digit", "1/0/16", "16", false},
-		{"empty", "", "", true},
-		{"non-numeric", "abc", "", true},
+		{"single digit", "1", portName{Interface: "1", Port: "1"}, false},
+		{"double digit", "16", portName{Interface: "16", Port: "16"}, false},
+		{"with leading zero", "01", portName{Interface: "1", Port: "1"}, false},
+		{"tplink format", "1/0/2", portName{Interface: "1/0/2", Unit: "1", Slot: "0", Port: "2"}, false},
+		{"tplink format double digit", "1/0/16", portName{Interface: "1/0/16", Unit: "1", Slot: "0", Port: "16"}, false},
+		{"stack member", "2/0/5", portName{Interface: "2/0/5", Unit: "2", Slot: "0", Port: "5"}, false},
+		{"expansion slot", " 1/1/03 ", portName{Interface: "1/1/3", Unit: "1", Slot: "1", Port: "3"}, false},
+		{"empty", "", portName{}, true},
+		{"non-numeric", "abc", portName{}, true},
+		{"non-numeric component", "1/0/x", portName{}, true},
+		{"two components", "1/5", portName{}, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := parsePort(tt.input)
+			got, err := parseInterface(tt.input)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePort() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("parseInterface() error = %v, wantErr %v", err, tt.wantErr)
 
 				return
 			}
 
 			if got != tt.want {
-				t.Errorf("parsePort() = %v, want %v", got, tt.want)
+				t.Errorf("parseInterface() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestDBmToWatts(t *testing.T) {
+	assert.InDelta(t, 0.001, dBmToWatts(0), 1e-12)
+	assert.InDelta(t, 0.0001, dBmToWatts(-10), 1e-12)
+	assert.InDelta(t, 0.002, dBmToWatts(3.0103), 1e-6)
+	assert.InDelta(t, 1e-7, dBmToWatts(-40), 1e-15)
+	assert.Zero(t, dBmToWatts(math.Inf(-1)))
+}
+
+func TestParseReading(t *testing.T) {
+	t.Parallel()
+
+	m := &DDMMetrics{}
+
+	assert.InDelta(t, -3.52, m.parseReading(FieldTxPower, "-3.520000"), 0.001)
+	assert.True(t, m.Valid(FieldTxPower))
+
+	// placeholders and empty values are missing, not errors
+	for _, s := range []string{"", "  ", "--", "---", "N/A"} {
+		assert.Zero(t, m.parseReading(FieldRxPower, s), s)
+	}
+
+	assert.False(t, m.Valid(FieldRxPower))
+	assert.Empty(t, m.ParseErrors)
+
+	// garbage is an error
+	assert.Zero(t, m.parseReading(FieldTemperature, "0x1f"))
+	assert.False(t, m.Valid(FieldTemperature))
+	assert.Equal(t, []string{FieldTemperature}, m.ParseErrors)
+
+	assert.Equal(t, "voltage_low_warning", thresholdField(FieldVoltage, "low", "warning"))
+}
@@ -0,0 +1,218 @@
+package tplinkddm
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PollTarget is a switch for the Poller to walk
+type PollTarget struct {
+	Client SNMPGetter
+	Name   string
+}
+
+// Poller walks switches in the background on a fixed interval and caches
+// each one's latest result, so scrapes are served without walking the
+// switch. Several Prometheus replicas scraping the same switch then cost one
+// walk per interval rather than one per scrape.
+type Poller struct {
+	targets   func() []PollTarget
+	results   map[string]*polledResult
+	scheduled map[string]PollTarget
+	interval  time.Duration
+	mu        sync.RWMutex
+}
+
+// polledResult is the outcome of a target's latest poll
+type polledResult struct {
+	lastSuccess time.Time
+	result      *DDMResult
+	err         error
+}
+
+// NewPoller creates a poller walking the targets returned by targets every
+// interval. targets is called once per interval, so it can follow
+// configuration reloads.
+func NewPoller(interval time.Duration, targets func() []PollTarget) *Poller {
+	return &Poller{
+		targets:   targets,
+		results:   map[string]*polledResult{},
+		scheduled: map[string]PollTarget{},
+		interval:  interval,
+	}
+}
+
+// Run polls each target immediately and then every interval, until ctx is
+// done. Each target is on its own schedule, so a slow or unreachable switch
+// doesn't delay the others. New targets are picked up, and removed ones
+// stopped, once per interval.
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	cancels := map[string]context.CancelFunc{}
+
+	for {
+		targets := p.targets()
+		current := make(map[string]bool, len(targets))
+
+		p.mu.Lock()
+
+		for _, t := range targets {
+			current[t.Name] = true
+			p.scheduled[t.Name] = t
+
+			if _, ok := cancels[t.Name]; !ok {
+				tctx, cancel := context.WithCancel(ctx)
+				cancels[t.Name] = cancel
+
+				wg.Go(func() {
+					p.schedule(tctx, t.Name)
+				})
+			}
+		}
+
+		for name, cancel := range cancels {
+			if !current[name] {
+				cancel()
+				delete(cancels, name)
+				delete(p.scheduled, name)
+			}
+		}
+
+		p.forget(current)
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// schedule polls the named target immediately and then every interval,
+// until ctx is done. Each poll uses the target's latest settings.
+func (p *Poller) schedule(ctx context.Context, name string) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.mu.RLock()
+		t, ok := p.scheduled[name]
+		p.mu.RUnlock()
+
+		// the target was removed
+		if !ok || ctx.Err() != nil {
+			return
+		}
+
+		p.poll(ctx, t)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll walks all targets once, concurrently, and waits for them to finish.
+// Cached results for targets which are no longer returned are dropped.
+func (p *Poller) Poll(ctx context.Context) {
+	targets := p.targets()
+
+	var wg sync.WaitGroup
+
+	for _, t := range targets {
+		wg.Go(func() {
+			p.poll(ctx, t)
+		})
+	}
+
+	wg.Wait()
+
+	current := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		current[t.Name] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.forget(current)
+}
+
+// forget drops the cached results of targets not in current. p.mu must be
+// held.
+func (p *Poller) forget(current map[string]bool) {
+	for name := range p.results {
+		if !current[name] {
+			delete(p.results, name)
+		}
+	}
+}
+
+func (p *Poller) poll(ctx context.Context, t PollTarget) {
+	result, err := t.Client.GetDDMMetrics(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "failed to poll target", "target", t.Name, "error", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the target was removed while it was being walked
+	if ctx.Err() != nil {
+		return
+	}
+
+	r, ok := p.results[t.Name]
+	if !ok {
+		r = &polledResult{}
+		p.results[t.Name] = r
+	}
+
+	r.result, r.err = result, err
+	if err == nil {
+		r.lastSuccess = result.Time
+	}
+}
+
+// Getter returns an SNMPGetter serving the named target's cached result. It
+// returns false if the target isn't polled, or hasn't been polled yet, in
+// which case the caller should walk the switch itself.
+func (p *Poller) Getter(name string) (SNMPGetter, bool) {
+	if p == nil {
+		return nil, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	r, ok := p.results[name]
+	if !ok {
+		return nil, false
+	}
+
+	return cachedResult(*r), true
+}
+
+// cachedResult is a snapshot of a target's latest poll
+type cachedResult polledResult
+
+// GetDDMMetrics implements SNMPGetter, returning the cached result
+func (r cachedResult) GetDDMMetrics(_ context.Context) (*DDMResult, error) {
+	return r.result, r.err
+}
+
+// LastSuccess returns when the target was last walked successfully, or the
+// zero time if it never was
+func (r cachedResult) LastSuccess() time.Time {
+	return r.lastSuccess
+}
@@ -0,0 +1,151 @@
+package tplinkddm
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClient counts walks, returning a fixed result or error
+type countingClient struct {
+	result *DDMResult
+	err    atomic.Pointer[error]
+	walks  atomic.Int32
+}
+
+func (c *countingClient) GetDDMMetrics(_ context.Context) (*DDMResult, error) {
+	c.walks.Add(1)
+
+	if err := c.err.Load(); err != nil {
+		return nil, *err
+	}
+
+	return c.result, nil
+}
+
+func TestPoller(t *testing.T) {
+	walked := time.Unix(1700000000, 0)
+	client := &countingClient{result: &DDMResult{Time: walked, SysName: "sw"}}
+
+	targets := []PollTarget{{Name: "sw1", Client: client}}
+	p := NewPoller(time.Minute, func() []PollTarget { return targets })
+
+	// not polled yet, so scrapes walk the switch themselves
+	_, ok := p.Getter("sw1")
+	assert.False(t, ok)
+
+	p.Poll(t.Context())
+
+	getter, ok := p.Getter("sw1")
+	require.True(t, ok)
+
+	// scrapes are served from the cache, without walking again
+	for range 3 {
+		result, err := getter.GetDDMMetrics(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "sw", result.SysName)
+	}
+
+	assert.Equal(t, int32(1), client.walks.Load())
+
+	_, ok = p.Getter("unlisted")
+	assert.False(t, ok)
+
+	// a failed poll is served as a failure, remembering the last success
+	err := ErrNoData
+	client.err.Store(&err)
+	p.Poll(t.Context())
+
+	getter, ok = p.Getter("sw1")
+	require.True(t, ok)
+
+	_, err = getter.GetDDMMetrics(t.Context())
+	require.ErrorIs(t, err, ErrNoData)
+	assert.Equal(t, walked, getter.(lastSuccessReporter).LastSuccess())
+
+	// targets removed from the configuration are forgotten
+	targets = nil
+	p.Poll(t.Context())
+
+	_, ok = p.Getter("sw1")
+	assert.False(t, ok)
+}
+
+func TestPoller_Run(t *testing.T) {
+	client := &countingClient{result: &DDMResult{Time: time.Now()}}
+	p := NewPoller(10*time.Millisecond, func() []PollTarget {
+		return []PollTarget{{Name: "sw1", Client: client}}
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return client.walks.Load() >= 3 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestPoller_RunSlowTarget(t *testing.T) {
+	slow := &blockingClient{release: make(chan struct{})}
+	fast := &countingClient{result: &DDMResult{Time: time.Now()}}
+
+	var removed atomic.Bool
+
+	p := NewPoller(10*time.Millisecond, func() []PollTarget {
+		if removed.Load() {
+			return []PollTarget{{Name: "fast", Client: fast}}
+		}
+
+		return []PollTarget{{Name: "slow", Client: slow}, {Name: "fast", Client: fast}}
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	// the fast target keeps being polled while the slow one is stuck
+	assert.Eventually(t, func() bool { return fast.walks.Load() >= 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, slow.count())
+
+	_, ok := p.Getter("slow")
+	assert.False(t, ok)
+
+	// a removed target's walk is cancelled, and its result isn't cached
+	removed.Store(true)
+
+	assert.Eventually(t, func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		_, ok := p.scheduled["slow"]
+
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+
+	_, ok = p.Getter("slow")
+	assert.False(t, ok)
+
+	cancel()
+	<-done
+}
+
+func TestPoller_NilGetter(t *testing.T) {
+	var p *Poller
+
+	_, ok := p.Getter("sw1")
+	assert.False(t, ok)
+}
@@ -0,0 +1,129 @@
+package tplinkddm
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ConfigReloader holds the current configuration file and atomically swaps
+// it on reload. Scrapes which have already fetched the old configuration keep
+// using it, and a configuration that fails to load never replaces a good one.
+type ConfigReloader struct {
+	cfg  atomic.Pointer[Config]
+	path string
+	mu   sync.Mutex // serializes reloads
+
+	discovery *FileDiscovery // nil unless file_sd is in use
+
+	lastReloadSuccessful prometheus.Gauge
+	lastReloadSuccess    prometheus.Gauge
+}
+
+// NewConfigReloader loads the configuration file at path. An empty path
+// gives a reloader with no configuration, for which reloading is a no-op.
+func NewConfigReloader(path string) (*ConfigReloader, error) {
+	r := &ConfigReloader{
+		path: path,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful (1 = success, 0 = failure)",
+		}),
+		lastReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "tplink_ddm_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload",
+		}),
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Config returns the current configuration, or nil if no file is configured
+func (r *ConfigReloader) Config() *Config {
+	return r.cfg.Load()
+}
+
+// Reload re-reads the configuration file, keeping the current configuration
+// if the new one is invalid.
+func (r *ConfigReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.path == "" {
+		r.markReload(true)
+
+		return nil
+	}
+
+	cfg, err := LoadConfig(r.path)
+	if err != nil {
+		r.markReload(false)
+
+		return err
+	}
+
+	r.store(cfg)
+	r.markReload(true)
+
+	slog.Info("loaded configuration", "file", r.path, "targets", len(cfg.Targets), "auths", len(cfg.Auths))
+
+	return nil
+}
+
+// SetFileDiscovery has d discover targets from the file_sd_configs of the
+// current configuration and of each one reloaded later, and includes its
+// targets in the configurations returned by Config.
+func (r *ConfigReloader) SetFileDiscovery(d *FileDiscovery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.discovery = d
+
+	if cfg := r.cfg.Load(); cfg != nil {
+		r.store(cfg)
+	}
+}
+
+// store makes cfg the current configuration, pointing file discovery at its
+// file_sd_configs. cfg is copied, since scrapes may be using it already.
+func (r *ConfigReloader) store(cfg *Config) {
+	if r.discovery != nil {
+		c := *cfg
+		c.discovery = r.discovery
+		cfg = &c
+
+		r.discovery.update(cfg)
+	}
+
+	r.cfg.Store(cfg)
+}
+
+func (r *ConfigReloader) markReload(success bool) {
+	if !success {
+		r.lastReloadSuccessful.Set(0)
+
+		return
+	}
+
+	r.lastReloadSuccessful.Set(1)
+	r.lastReloadSuccess.Set(float64(time.Now().Unix()))
+}
+
+// Describe implements prometheus.Collector
+func (r *ConfigReloader) Describe(ch chan<- *prometheus.Desc) {
+	r.lastReloadSuccessful.Describe(ch)
+	r.lastReloadSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (r *ConfigReloader) Collect(ch chan<- prometheus.Metric) {
+	r.lastReloadSuccessful.Collect(ch)
+	r.lastReloadSuccess.Collect(ch)
+}
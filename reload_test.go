@@ -0,0 +1,57 @@
+package tplinkddm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("targets:\n  - address: 10.0.0.1\n"), 0o600))
+
+	r, err := NewConfigReloader(path)
+	require.NoError(t, err)
+
+	old := r.Config()
+	require.Len(t, old.Targets, 1)
+	assert.InDelta(t, 1, testutil.ToFloat64(r.lastReloadSuccessful), 0)
+	assert.Positive(t, testutil.ToFloat64(r.lastReloadSuccess))
+
+	// a valid change is swapped in, without touching the old config
+	require.NoError(t, os.WriteFile(path, []byte("targets:\n  - address: 10.0.0.1\n  - address: 10.0.0.2\n"), 0o600))
+	require.NoError(t, r.Reload())
+	assert.Len(t, r.Config().Targets, 2)
+	assert.Len(t, old.Targets, 1)
+
+	// an invalid change is rejected and the current config kept
+	require.NoError(t, os.WriteFile(path, []byte("targets:\n  - name: no-address\n"), 0o600))
+	require.Error(t, r.Reload())
+	assert.Len(t, r.Config().Targets, 2)
+	assert.InDelta(t, 0, testutil.ToFloat64(r.lastReloadSuccessful), 0)
+
+	err = testutil.CollectAndCompare(r, strings.NewReader(`
+# HELP tplink_ddm_exporter_config_last_reload_successful Whether the last configuration reload attempt was successful (1 = success, 0 = failure)
+# TYPE tplink_ddm_exporter_config_last_reload_successful gauge
+tplink_ddm_exporter_config_last_reload_successful 0
+`), "tplink_ddm_exporter_config_last_reload_successful")
+	assert.NoError(t, err)
+}
+
+func TestConfigReloader_InitialLoadFails(t *testing.T) {
+	_, err := NewConfigReloader(filepath.Join(t.TempDir(), "missing.yml"))
+	assert.Error(t, err)
+}
+
+func TestConfigReloader_NoFile(t *testing.T) {
+	r, err := NewConfigReloader("")
+	require.NoError(t, err)
+	assert.Nil(t, r.Config())
+	require.NoError(t, r.Reload())
+	assert.InDelta(t, 1, testutil.ToFloat64(r.lastReloadSuccessful), 0)
+}
@@ -0,0 +1,39 @@
+package tplinkddm
+
+import (
+	"context"
+	"maps"
+)
+
+// SDTargetGroup is a target group in the format of Prometheus' HTTP and file
+// service discovery (http_sd_configs and file_sd_configs)
+type SDTargetGroup struct {
+	Labels  map[string]string `json:"labels"  yaml:"labels"`
+	Targets []string          `json:"targets" yaml:"targets"`
+}
+
+// ServiceDiscovery returns an HTTP service discovery target group for each
+// configured target, including those listed in DNS by the target groups and
+// discovered from file_sd files. Each carries the target's labels, and the
+// /scrape parameters selecting it and its auth, so Prometheus only needs to
+// relabel __address__ to point at the exporter.
+func (c *Config) ServiceDiscovery(ctx context.Context, r *Resolver) []SDTargetGroup {
+	targets := c.ResolveTargets(ctx, r)
+	groups := make([]SDTargetGroup, 0, len(targets))
+
+	for _, t := range targets {
+		labels := make(map[string]string, len(t.Labels)+3)
+		maps.Copy(labels, t.Labels)
+
+		labels["__metrics_path__"] = "/scrape"
+		labels["__param_target"] = t.Name
+
+		if t.Auth != "" {
+			labels["__param_auth"] = t.Auth
+		}
+
+		groups = append(groups, SDTargetGroup{Targets: []string{t.Name}, Labels: labels})
+	}
+
+	return groups
+}
@@ -0,0 +1,57 @@
+package tplinkddm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ServiceDiscovery(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+auths:
+  v3:
+    version: 3
+    username: monitor
+    security_level: noAuthNoPriv
+targets:
+  - name: core-1
+    address: 10.0.0.1
+    auth: v3
+    labels:
+      site: ams1
+  - address: 10.0.0.2
+target_groups:
+  - a: edge.example.com
+`))
+	require.NoError(t, err)
+
+	r := newResolver(&fakeDNS{hosts: map[string][]string{"edge.example.com": {"10.0.1.1"}}}, time.Hour)
+
+	groups := cfg.ServiceDiscovery(t.Context(), r)
+	assert.Equal(t, []SDTargetGroup{
+		{
+			Targets: []string{"core-1"},
+			Labels: map[string]string{
+				"__metrics_path__": "/scrape", "__param_target": "core-1", "__param_auth": "v3", "site": "ams1",
+			},
+		},
+		{
+			Targets: []string{"10.0.0.2"},
+			Labels:  map[string]string{"__metrics_path__": "/scrape", "__param_target": "10.0.0.2"},
+		},
+		{
+			Targets: []string{"10.0.1.1"},
+			Labels:  map[string]string{"__metrics_path__": "/scrape", "__param_target": "10.0.1.1"},
+		},
+	}, groups)
+
+	// Prometheus wants a list, even when there are no targets
+	var nilCfg *Config
+
+	b, err := json.Marshal(nilCfg.ServiceDiscovery(t.Context(), newResolver(&fakeDNS{}, 0)))
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(b))
+}
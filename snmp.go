@@ -2,8 +2,10 @@ package tplinkddm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -41,6 +43,15 @@ const (
 	oidDDMStatusLossSignal = "1.3.6.1.4.1.11863.6.96.1.7.1.1.8" // Loss of Signal (LOS)
 	oidDDMStatusTxFault    = "1.3.6.1.4.1.11863.6.96.1.7.1.1.9" // Transmitter fault
 
+	// Transceiver information (table .1.8.1.1)
+	oidSFPInfoVendor     = "1.3.6.1.4.1.11863.6.96.1.8.1.1.2"
+	oidSFPInfoPartNumber = "1.3.6.1.4.1.11863.6.96.1.8.1.1.3"
+	oidSFPInfoSerial     = "1.3.6.1.4.1.11863.6.96.1.8.1.1.4"
+	oidSFPInfoRevision   = "1.3.6.1.4.1.11863.6.96.1.8.1.1.5"
+	oidSFPInfoWavelength = "1.3.6.1.4.1.11863.6.96.1.8.1.1.6" // nm
+	oidSFPInfoConnector  = "1.3.6.1.4.1.11863.6.96.1.8.1.1.7" // e.g. "LC"
+	oidSFPInfoMediaType  = "1.3.6.1.4.1.11863.6.96.1.8.1.1.8" // e.g. "10GBASE-LR"
+
 	// RX Power thresholds (table .1.2.1.1)
 	oidDDMRxPowerHighAlarm   = "1.3.6.1.4.1.11863.6.96.1.2.1.1.2"
 	oidDDMRxPowerLowAlarm    = "1.3.6.1.4.1.11863.6.96.1.2.1.1.3"
@@ -72,24 +83,104 @@ const (
 	oidDDMTemperatureLowWarning  = "1.3.6.1.4.1.11863.6.96.1.6.1.1.5"
 )
 
+// Errors returned by GetDDMMetrics, for classifying failed scrapes
+var (
+	ErrConnect = errors.New("SNMP connect failed")
+	ErrNoData  = errors.New("no DDM port data")
+	ErrParse   = errors.New("no parseable DDM ports")
+)
+
 // SNMPClient wraps gosnmp for TP-Link DDM queries
 type SNMPClient struct {
-	target    string
-	community string
+	target         string
+	transport      string
+	auth           Auth
+	timeout        time.Duration
+	retries        int
+	maxRepetitions uint32
+	port           uint16
+	resolver       *Resolver
+}
+
+// ClientOption configures an SNMPClient
+type ClientOption func(*SNMPClient)
+
+// WithPort sets the SNMP port (UDP or TCP, default 161)
+func WithPort(port uint16) ClientOption {
+	return func(c *SNMPClient) {
+		c.port = port
+	}
+}
+
+// WithTimeout sets the timeout for each SNMP request (default 2s)
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *SNMPClient) {
+		c.timeout = timeout
+	}
+}
+
+// WithResolver looks up hostname targets with r, so their answers are
+// cached between scrapes
+func WithResolver(r *Resolver) ClientOption {
+	return func(c *SNMPClient) {
+		c.resolver = r
+	}
+}
+
+// WithTransport sets the SNMP transport, TransportUDP (the default) or
+// TransportTCP
+func WithTransport(transport string) ClientOption {
+	return func(c *SNMPClient) {
+		c.transport = transport
+	}
+}
+
+// WithMaxRepetitions sets the number of rows fetched by each GetBulk
+// request (default 50). Lower values help switches which drop large
+// responses; higher values need fewer round trips on big stacks.
+func WithMaxRepetitions(n uint32) ClientOption {
+	return func(c *SNMPClient) {
+		c.maxRepetitions = n
+	}
+}
+
+// WithRetries sets the number of retries for each SNMP request (default 1)
+func WithRetries(retries int) ClientOption {
+	return func(c *SNMPClient) {
+		c.retries = retries
+	}
 }
 
 // DDMMetrics holds parsed DDM values for a port
 type DDMMetrics struct {
 	// Strings (16 bytes each on 64-bit)
-	Port          string
+	Port          string // port number, e.g. "5"
+	Interface     string // full interface name, e.g. "1/0/5"
+	Unit          string // stack unit (empty if the switch reports plain port numbers)
+	Slot          string
 	LAGMembership string // LAG/trunk membership (empty if not in LAG)
 
+	// Transceiver inventory (empty if the switch doesn't report it)
+	Vendor     string
+	PartNumber string
+	Serial     string
+	Revision   string
+	Connector  string
+	MediaType  string
+
+	// IF-MIB correlation (IfIndex is empty if no interface matched)
+	IfIndex string
+	IfName  string
+	IfAlias string
+
 	// Float64 values (8 bytes each)
 	Temperature float64
 	Voltage     float64
 	BiasCurrent float64
 	TxPower     float64
 	RxPower     float64
+	Speed       float64 // IF-MIB ifHighSpeed, in bits per second
+	Wavelength  float64 // transceiver laser wavelength, in nm
 
 	// Temperature thresholds (Celsius)
 	TemperatureHighAlarm   float64
@@ -121,57 +212,91 @@ type DDMMetrics struct {
 	RxPowerHighWarning float64
 	RxPowerLowWarning  float64
 
+	// Readings which were missing or unparseable, keyed by field name (see
+	// Valid), and the fields which held something other than a number
+	Invalid     map[string]bool
+	ParseErrors []string
+
 	// Int (8 bytes on 64-bit)
 	ShutdownPolicy int // Port shutdown policy: 0=none, 1=warning, 2=alarm
+	OperStatus     int // IF-MIB ifOperStatus: 1=up, 2=down, 3=testing, ...
 
 	// Bools (1 byte each, but padded)
 	DDMEnabled   bool // DDM monitoring enabled on port
 	DDMSupported bool // SFP supports DDM
 	LossOfSignal bool // SFP reports signal loss (LOS)
 	TxFault      bool // SFP reports transmitter fault
+	Empty        bool // no transceiver is inserted in the port
 }
 
 // DDMResult holds the complete result of a DDM scrape
 type DDMResult struct {
+	Time    time.Time // when the walk completed
 	SysName string
+	System  SystemInfo
 	Metrics []DDMMetrics
+	PDUs    int // number of PDUs returned by the walk
 }
 
-// NewSNMPClient creates a new SNMP client
+// NewSNMPClient creates a new SNMP v2c client
 func NewSNMPClient(target, community string) *SNMPClient {
-	return &SNMPClient{
+	return NewSNMPClientWithAuth(target, Auth{Version: 2, Community: community})
+}
+
+// NewSNMPClientWithAuth creates a new SNMP client using the given
+// credentials. target is a bare hostname or IP; see TargetConfig.NewClient
+// for target addresses with a scheme or port.
+func NewSNMPClientWithAuth(target string, auth Auth, opts ...ClientOption) *SNMPClient {
+	c := &SNMPClient{
 		target:    target,
-		community: community,
+		transport: TransportUDP,
+		auth:      auth,
+		timeout:   2 * time.Second,
+		retries:   1,
+		port:      161,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 //nolint:gochecknoglobals // package-level tracer is the OTel convention
 var tracer = otel.Tracer("github.com/hairyhenderson/tplink-ddm-exporter")
 
-// GetDDMMetrics queries all DDM metrics and sysName from the switch
+// fitTimeout shortens the per-request timeout so that a request and all of
+// its retries finish within the time remaining before the scrape's deadline
+func fitTimeout(timeout time.Duration, retries int, remaining time.Duration) time.Duration {
+	attempts := time.Duration(retries + 1)
+	if remaining <= 0 || timeout*attempts <= remaining {
+		return timeout
+	}
+
+	return remaining / attempts
+}
+
+// GetDDMMetrics queries all DDM metrics and sysName from the switch. If ctx
+// has a deadline, the per-request timeout is shortened to fit it.
 func (c *SNMPClient) GetDDMMetrics(ctx context.Context) (*DDMResult, error) {
 	ctx, span := tracer.Start(ctx, "SNMPClient.GetDDMMetrics",
 		trace.WithAttributes(
 			attribute.String("snmp.target", c.target),
+			attribute.Int("snmp.version", c.auth.Version),
 		),
 	)
 	defer span.End()
 
-	client := &gosnmp.GoSNMP{
-		Target:    c.target,
-		Port:      161,
-		Community: c.community,
-		Version:   gosnmp.Version2c,
-		Timeout:   2 * time.Second,
-		Retries:   1,
+	timeout := c.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = fitTimeout(c.timeout, c.retries, time.Until(deadline))
+		span.SetAttributes(attribute.String("snmp.timeout", timeout.String()))
 	}
 
-	err := client.Connect()
+	client, err := c.dial(ctx, timeout)
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "SNMP connect failed")
-
-		return nil, fmt.Errorf("SNMP connect failed: %w", err)
+		return nil, err
 	}
 
 	defer func() {
@@ -196,126 +321,267 @@ func (c *SNMPClient) GetDDMMetrics(ctx context.Context) (*DDMResult, error) {
 
 	span.SetAttributes(attribute.Int("metrics.count", len(metrics)))
 
+	if len(metrics) == 0 {
+		err = fmt.Errorf("%w: none of %d rows could be parsed", ErrParse, len(ddmData.rows))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "parse failed")
+
+		return nil, err
+	}
+
 	return &DDMResult{
+		Time:    time.Now(),
 		SysName: ddmData.sysName,
+		System:  ddmData.system,
 		Metrics: metrics,
+		PDUs:    ddmData.pduCount,
 	}, nil
 }
 
+// dial resolves the target if needed and connects to it, recording failures
+// on the span in ctx. The caller closes the connection.
+func (c *SNMPClient) dial(ctx context.Context, timeout time.Duration) (*gosnmp.GoSNMP, error) {
+	span := trace.SpanFromContext(ctx)
+	host := c.target
+
+	if c.resolver != nil {
+		addrs, err := c.resolver.LookupHost(ctx, c.target)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "DNS lookup failed")
+
+			return nil, fmt.Errorf("%w: %w", ErrConnect, err)
+		}
+
+		host = addrs[0]
+		span.SetAttributes(attribute.String("snmp.address", host))
+	}
+
+	client := &gosnmp.GoSNMP{
+		Context:        ctx,
+		Target:         host,
+		Transport:      c.transport,
+		Port:           c.port,
+		Timeout:        timeout,
+		Retries:        c.retries,
+		MaxRepetitions: c.maxRepetitions,
+	}
+
+	if err := c.auth.configure(client); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "invalid auth")
+
+		return nil, err
+	}
+
+	if err := client.Connect(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "SNMP connect failed")
+
+		return nil, fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+
+	return client, nil
+}
+
+// ddmWalkData holds the raw values from one walk of oidDDMRoot, with each
+// table row keyed by its OID index suffix, so values from different columns
+// are only ever joined when they belong to the same row.
 type ddmWalkData struct {
-	sysName string
+	rows       map[string]*ddmRow
+	ifRows     map[string]*ifRow     // IF-MIB values, keyed by ifIndex
+	entityRows map[string]*entityRow // ENTITY-MIB values, keyed by entPhysicalIndex
+	sysName    string
+	system     SystemInfo
+	pduCount   int
+}
+
+// ddmRow holds the raw string values of one port's row across all DDM
+// tables. Columns the switch didn't return are left empty.
+type ddmRow struct {
 	// Current values
-	ports        []string
-	temps        []string
-	voltages     []string
-	biasCurrents []string
-	txPowers     []string
-	rxPowers     []string
+	port        string
+	temp        string
+	voltage     string
+	biasCurrent string
+	txPower     string
+	rxPower     string
 
 	// Configuration
-	ddmEnabled     []string
-	shutdownPolicy []string
-	lagMembership  []string
+	ddmEnabled     string
+	shutdownPolicy string
+	lagMembership  string
 
 	// Status flags
-	ddmSupported []string
-	lossOfSignal []string
-	txFault      []string
+	ddmSupported string
+	lossOfSignal string
+	txFault      string
+
+	// Transceiver information
+	vendor     string
+	partNumber string
+	serial     string
+	revision   string
+	wavelength string
+	connector  string
+	mediaType  string
 
 	// Temperature thresholds
-	tempHighAlarm   []string
-	tempLowAlarm    []string
-	tempHighWarning []string
-	tempLowWarning  []string
+	tempHighAlarm   string
+	tempLowAlarm    string
+	tempHighWarning string
+	tempLowWarning  string
 
 	// Voltage thresholds
-	voltageHighAlarm   []string
-	voltageLowAlarm    []string
-	voltageHighWarning []string
-	voltageLowWarning  []string
+	voltageHighAlarm   string
+	voltageLowAlarm    string
+	voltageHighWarning string
+	voltageLowWarning  string
 
 	// Bias Current thresholds
-	biasCurrentHighAlarm   []string
-	biasCurrentLowAlarm    []string
-	biasCurrentHighWarning []string
-	biasCurrentLowWarning  []string
+	biasCurrentHighAlarm   string
+	biasCurrentLowAlarm    string
+	biasCurrentHighWarning string
+	biasCurrentLowWarning  string
 
 	// TX Power thresholds
-	txPowerHighAlarm   []string
-	txPowerLowAlarm    []string
-	txPowerHighWarning []string
-	txPowerLowWarning  []string
+	txPowerHighAlarm   string
+	txPowerLowAlarm    string
+	txPowerHighWarning string
+	txPowerLowWarning  string
 
 	// RX Power thresholds
-	rxPowerHighAlarm   []string
-	rxPowerLowAlarm    []string
-	rxPowerHighWarning []string
-	rxPowerLowWarning  []string
+	rxPowerHighAlarm   string
+	rxPowerLowAlarm    string
+	rxPowerHighWarning string
+	rxPowerLowWarning  string
+}
+
+// row returns the row with the given index, creating it if needed
+func (d *ddmWalkData) row(index string) *ddmRow {
+	if d.rows == nil {
+		d.rows = map[string]*ddmRow{}
+	}
+
+	r, ok := d.rows[index]
+	if !ok {
+		r = &ddmRow{}
+		d.rows[index] = r
+	}
+
+	return r
+}
+
+// indexes returns the row indexes in OID order
+func (d *ddmWalkData) indexes() []string {
+	return sortedKeys(d.rows)
+}
+
+// sortedKeys returns the OID index keys of m in OID order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.SortFunc(keys, compareOIDs)
+
+	return keys
+}
+
+// compareOIDs compares two dotted OIDs (or OID index suffixes) numerically,
+// component by component
+func compareOIDs(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "."), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "."), ".")
+
+	for i := range min(len(as), len(bs)) {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+
+		if x != y {
+			return x - y
+		}
+	}
+
+	return len(as) - len(bs)
 }
 
 // pduToString extracts a string value from an SNMP PDU, returning false for
 // unsupported types.
 func pduToString(pdu gosnmp.SnmpPDU) (string, bool) {
-	//nolint:exhaustive // only OctetString and Integer are expected from TP-Link DDM
+	//nolint:exhaustive // only strings, integers and IF-MIB gauges are expected
 	switch pdu.Type {
 	case gosnmp.OctetString:
 		return string(pdu.Value.([]byte)), true
 	case gosnmp.Integer:
 		return strconv.Itoa(pdu.Value.(int)), true
+	case gosnmp.Gauge32:
+		return gosnmp.ToBigInt(pdu.Value).String(), true
 	default:
 		return "", false
 	}
 }
 
 // buildOIDDispatch creates a mapping from OID column prefix to the
-// corresponding field in ddmWalkData. Used to dispatch PDUs from a single
-// root BulkWalk into the correct slices.
-func buildOIDDispatch(data *ddmWalkData) map[string]*[]string {
-	return map[string]*[]string{
-		oidDDMStatusPort:        &data.ports,
-		oidDDMStatusTemperature: &data.temps,
-		oidDDMStatusVoltage:     &data.voltages,
-		oidDDMStatusBiasCurrent: &data.biasCurrents,
-		oidDDMStatusTxPower:     &data.txPowers,
-		oidDDMStatusRxPower:     &data.rxPowers,
-		oidDDMStatusSupported:   &data.ddmSupported,
-		oidDDMStatusLossSignal:  &data.lossOfSignal,
-		oidDDMStatusTxFault:     &data.txFault,
-
-		oidDDMConfigStatus:   &data.ddmEnabled,
-		oidDDMConfigShutdown: &data.shutdownPolicy,
-		oidDDMConfigPortLAG:  &data.lagMembership,
-
-		oidDDMRxPowerHighAlarm:   &data.rxPowerHighAlarm,
-		oidDDMRxPowerLowAlarm:    &data.rxPowerLowAlarm,
-		oidDDMRxPowerHighWarning: &data.rxPowerHighWarning,
-		oidDDMRxPowerLowWarning:  &data.rxPowerLowWarning,
-
-		oidDDMVoltageHighAlarm:   &data.voltageHighAlarm,
-		oidDDMVoltageLowAlarm:    &data.voltageLowAlarm,
-		oidDDMVoltageHighWarning: &data.voltageHighWarning,
-		oidDDMVoltageLowWarning:  &data.voltageLowWarning,
-
-		oidDDMBiasCurrentHighAlarm:   &data.biasCurrentHighAlarm,
-		oidDDMBiasCurrentLowAlarm:    &data.biasCurrentLowAlarm,
-		oidDDMBiasCurrentHighWarning: &data.biasCurrentHighWarning,
-		oidDDMBiasCurrentLowWarning:  &data.biasCurrentLowWarning,
-
-		oidDDMTxPowerHighAlarm:   &data.txPowerHighAlarm,
-		oidDDMTxPowerLowAlarm:    &data.txPowerLowAlarm,
-		oidDDMTxPowerHighWarning: &data.txPowerHighWarning,
-		oidDDMTxPowerLowWarning:  &data.txPowerLowWarning,
-
-		oidDDMTemperatureHighAlarm:   &data.tempHighAlarm,
-		oidDDMTemperatureLowAlarm:    &data.tempLowAlarm,
-		oidDDMTemperatureHighWarning: &data.tempHighWarning,
-		oidDDMTemperatureLowWarning:  &data.tempLowWarning,
+// corresponding field in ddmRow. Used to dispatch PDUs from a single root
+// BulkWalk into the correct row and column.
+//
+//nolint:funlen // one entry per column
+func buildOIDDispatch() map[string]func(*ddmRow) *string {
+	return map[string]func(*ddmRow) *string{
+		oidDDMStatusPort:        func(r *ddmRow) *string { return &r.port },
+		oidDDMStatusTemperature: func(r *ddmRow) *string { return &r.temp },
+		oidDDMStatusVoltage:     func(r *ddmRow) *string { return &r.voltage },
+		oidDDMStatusBiasCurrent: func(r *ddmRow) *string { return &r.biasCurrent },
+		oidDDMStatusTxPower:     func(r *ddmRow) *string { return &r.txPower },
+		oidDDMStatusRxPower:     func(r *ddmRow) *string { return &r.rxPower },
+		oidDDMStatusSupported:   func(r *ddmRow) *string { return &r.ddmSupported },
+		oidDDMStatusLossSignal:  func(r *ddmRow) *string { return &r.lossOfSignal },
+		oidDDMStatusTxFault:     func(r *ddmRow) *string { return &r.txFault },
+
+		oidSFPInfoVendor:     func(r *ddmRow) *string { return &r.vendor },
+		oidSFPInfoPartNumber: func(r *ddmRow) *string { return &r.partNumber },
+		oidSFPInfoSerial:     func(r *ddmRow) *string { return &r.serial },
+		oidSFPInfoRevision:   func(r *ddmRow) *string { return &r.revision },
+		oidSFPInfoWavelength: func(r *ddmRow) *string { return &r.wavelength },
+		oidSFPInfoConnector:  func(r *ddmRow) *string { return &r.connector },
+		oidSFPInfoMediaType:  func(r *ddmRow) *string { return &r.mediaType },
+
+		oidDDMConfigStatus:   func(r *ddmRow) *string { return &r.ddmEnabled },
+		oidDDMConfigShutdown: func(r *ddmRow) *string { return &r.shutdownPolicy },
+		oidDDMConfigPortLAG:  func(r *ddmRow) *string { return &r.lagMembership },
+
+		oidDDMRxPowerHighAlarm:   func(r *ddmRow) *string { return &r.rxPowerHighAlarm },
+		oidDDMRxPowerLowAlarm:    func(r *ddmRow) *string { return &r.rxPowerLowAlarm },
+		oidDDMRxPowerHighWarning: func(r *ddmRow) *string { return &r.rxPowerHighWarning },
+		oidDDMRxPowerLowWarning:  func(r *ddmRow) *string { return &r.rxPowerLowWarning },
+
+		oidDDMVoltageHighAlarm:   func(r *ddmRow) *string { return &r.voltageHighAlarm },
+		oidDDMVoltageLowAlarm:    func(r *ddmRow) *string { return &r.voltageLowAlarm },
+		oidDDMVoltageHighWarning: func(r *ddmRow) *string { return &r.voltageHighWarning },
+		oidDDMVoltageLowWarning:  func(r *ddmRow) *string { return &r.voltageLowWarning },
+
+		oidDDMBiasCurrentHighAlarm:   func(r *ddmRow) *string { return &r.biasCurrentHighAlarm },
+		oidDDMBiasCurrentLowAlarm:    func(r *ddmRow) *string { return &r.biasCurrentLowAlarm },
+		oidDDMBiasCurrentHighWarning: func(r *ddmRow) *string { return &r.biasCurrentHighWarning },
+		oidDDMBiasCurrentLowWarning:  func(r *ddmRow) *string { return &r.biasCurrentLowWarning },
+
+		oidDDMTxPowerHighAlarm:   func(r *ddmRow) *string { return &r.txPowerHighAlarm },
+		oidDDMTxPowerLowAlarm:    func(r *ddmRow) *string { return &r.txPowerLowAlarm },
+		oidDDMTxPowerHighWarning: func(r *ddmRow) *string { return &r.txPowerHighWarning },
+		oidDDMTxPowerLowWarning:  func(r *ddmRow) *string { return &r.txPowerLowWarning },
+
+		oidDDMTemperatureHighAlarm:   func(r *ddmRow) *string { return &r.tempHighAlarm },
+		oidDDMTemperatureLowAlarm:    func(r *ddmRow) *string { return &r.tempLowAlarm },
+		oidDDMTemperatureHighWarning: func(r *ddmRow) *string { return &r.tempHighWarning },
+		oidDDMTemperatureLowWarning:  func(r *ddmRow) *string { return &r.tempLowWarning },
 	}
 }
 
-// dispatchPDU routes a single PDU to the correct ddmWalkData field based on
-// its OID prefix.
-func dispatchPDU(pdu gosnmp.SnmpPDU, dispatch map[string]*[]string) {
+// dispatchPDU stores a single PDU in the ddmWalkData row named by its OID
+// index suffix, in the column named by its OID prefix.
+func dispatchPDU(pdu gosnmp.SnmpPDU, dispatch map[string]func(*ddmRow) *string, data *ddmWalkData) {
 	val, ok := pduToString(pdu)
 	if !ok {
 		slog.Debug("unexpected SNMP type in root walk", "oid", pdu.Name, "type", pdu.Type)
@@ -324,39 +590,57 @@ func dispatchPDU(pdu gosnmp.SnmpPDU, dispatch map[string]*[]string) {
 	}
 
 	for prefix, field := range dispatch {
-		if strings.HasPrefix(pdu.Name, "."+prefix+".") {
-			*field = append(*field, val)
+		index, found := strings.CutPrefix(pdu.Name, "."+prefix+".")
+		if found && index != "" {
+			*field(data.row(index)) = val
 
 			return
 		}
 	}
 }
 
-func (c *SNMPClient) getSysName(ctx context.Context, client *gosnmp.GoSNMP) string {
-	_, span := tracer.Start(ctx, "SNMPClient.getSysName")
-	defer span.End()
+// tableColumn maps a table column OID to its field in a row struct
+type tableColumn[R any] struct {
+	field func(*R) *string
+	oid   string
+}
 
-	client.Context = ctx
+// walkColumns walks each column in turn, collecting the values into rows
+// keyed by OID index suffix. It stops at the first failed walk, returning
+// the rows collected so far along with the error.
+func walkColumns[R any](client *gosnmp.GoSNMP, cols []tableColumn[R], pduCount *int) (map[string]*R, error) {
+	rows := map[string]*R{}
 
-	result, err := client.Get([]string{oidSysName})
-	if err != nil {
-		slog.Debug("failed to get sysName", "error", err)
-		span.RecordError(err)
+	for _, col := range cols {
+		err := client.BulkWalk(col.oid, func(pdu gosnmp.SnmpPDU) error {
+			*pduCount++
 
-		return ""
-	}
+			index, found := strings.CutPrefix(pdu.Name, "."+col.oid+".")
+			if !found || index == "" {
+				return nil
+			}
+
+			val, ok := pduToString(pdu)
+			if !ok {
+				return nil
+			}
+
+			r, ok := rows[index]
+			if !ok {
+				r = new(R)
+				rows[index] = r
+			}
 
-	if len(result.Variables) > 0 {
-		pdu := result.Variables[0]
-		if pdu.Type == gosnmp.OctetString {
-			name := string(pdu.Value.([]byte))
-			span.SetAttributes(attribute.String("snmp.sysName", name))
+			*col.field(r) = val
 
-			return name
+			return nil
+		})
+		if err != nil {
+			return rows, fmt.Errorf("walk of %s failed: %w", col.oid, err)
 		}
 	}
 
-	return ""
+	return rows, nil
 }
 
 func (c *SNMPClient) walkAllOIDs(ctx context.Context, client *gosnmp.GoSNMP) (*ddmWalkData, error) {
@@ -365,17 +649,15 @@ func (c *SNMPClient) walkAllOIDs(ctx context.Context, client *gosnmp.GoSNMP) (*d
 
 	data := &ddmWalkData{}
 
-	data.sysName = c.getSysName(ctx, client)
+	data.sysName, data.system = c.getSystemInfo(ctx, client)
 
-	dispatch := buildOIDDispatch(data)
+	dispatch := buildOIDDispatch()
 	client.Context = ctx
 
-	var pduCount int
-
 	err := client.BulkWalk(oidDDMRoot, func(pdu gosnmp.SnmpPDU) error {
-		pduCount++
+		data.pduCount++
 
-		dispatchPDU(pdu, dispatch)
+		dispatchPDU(pdu, dispatch, data)
 
 		return nil
 	})
@@ -387,171 +669,173 @@ func (c *SNMPClient) walkAllOIDs(ctx context.Context, client *gosnmp.GoSNMP) (*d
 	}
 
 	span.SetAttributes(
-		attribute.Int("snmp.pdu_count", pduCount),
-		attribute.Int("snmp.ports", len(data.ports)),
+		attribute.Int("snmp.pdu_count", data.pduCount),
+		attribute.Int("snmp.rows", len(data.rows)),
 	)
 
-	if len(data.ports) == 0 {
-		return nil, fmt.Errorf("no DDM port data found in walk of %s", oidDDMRoot)
+	if len(data.rows) == 0 {
+		return nil, fmt.Errorf("%w found in walk of %s", ErrNoData, oidDDMRoot)
+	}
+
+	// IF-MIB, the system monitor tables and ENTITY-MIB only add to the DDM
+	// data, and not every model has them, so their walks are optional:
+	// failures are logged rather than failing the scrape. They would only
+	// fail once the scrape's deadline has passed, so don't send them then.
+	if ctx.Err() != nil {
+		slog.DebugContext(ctx, "skipping optional walks after deadline", "target", c.target)
+
+		return data, nil
+	}
+
+	c.walkIfMIB(ctx, client, data)
+	c.walkSysMonitor(ctx, client, data)
+
+	if data.needsEntityFallback() {
+		c.walkEntityMIB(ctx, client, data)
 	}
 
 	return data, nil
 }
 
-//nolint:gocognit,gocyclo,funlen // Parsing many DDM threshold fields
 func (c *SNMPClient) parseDDMMetrics(ctx context.Context, data *ddmWalkData) []DDMMetrics {
 	_, span := tracer.Start(ctx, "SNMPClient.parseDDMMetrics",
-		trace.WithAttributes(attribute.Int("port.count", len(data.ports))),
+		trace.WithAttributes(attribute.Int("row.count", len(data.rows))),
 	)
 	defer span.End()
 
-	metrics := make([]DDMMetrics, 0, len(data.ports))
+	metrics := make([]DDMMetrics, 0, len(data.rows))
 
-	for idx, portStr := range data.ports {
-		port, err := parsePort(portStr)
-		if err != nil {
-			slog.Warn("skipping invalid port", "port", portStr, "error", err)
+	for _, idx := range data.indexes() {
+		row := data.rows[idx]
+		if row.port == "" {
+			// a row only present in the config or threshold tables
+			slog.Debug("skipping row without port", "index", idx)
 
 			continue
 		}
 
-		m := DDMMetrics{Port: port}
-
-		// Current values
-		if idx < len(data.temps) {
-			m.Temperature, _ = parseFloat(data.temps[idx])
-		}
-
-		if idx < len(data.voltages) {
-			m.Voltage, _ = parseFloat(data.voltages[idx])
-		}
-
-		if idx < len(data.biasCurrents) {
-			m.BiasCurrent, _ = parseFloat(data.biasCurrents[idx])
-		}
-
-		if idx < len(data.txPowers) {
-			m.TxPower, _ = parseFloat(data.txPowers[idx])
-		}
-
-		if idx < len(data.rxPowers) {
-			m.RxPower, _ = parseFloat(data.rxPowers[idx])
-		}
-
-		// Configuration
-		if idx < len(data.ddmEnabled) {
-			// DDM config uses 0=disable, 1=enable (same as boolean)
-			m.DDMEnabled, _ = strconv.ParseBool(data.ddmEnabled[idx])
-		}
-
-		if idx < len(data.shutdownPolicy) {
-			m.ShutdownPolicy, _ = strconv.Atoi(data.shutdownPolicy[idx])
-		}
-
-		if idx < len(data.lagMembership) {
-			m.LAGMembership = data.lagMembership[idx]
-		}
-
-		// Status flags
-		if idx < len(data.ddmSupported) {
-			m.DDMSupported, _ = strconv.ParseBool(data.ddmSupported[idx])
-		}
-
-		if idx < len(data.lossOfSignal) {
-			m.LossOfSignal, _ = strconv.ParseBool(data.lossOfSignal[idx])
-		}
+		name, err := parseInterface(row.port)
+		if err != nil {
+			slog.Warn("skipping invalid port", "index", idx, "port", row.port, "error", err)
 
-		if idx < len(data.txFault) {
-			m.TxFault, _ = strconv.ParseBool(data.txFault[idx])
+			continue
 		}
 
-		// Temperature thresholds
-		if idx < len(data.tempHighAlarm) {
-			m.TemperatureHighAlarm, _ = parseFloat(data.tempHighAlarm[idx])
-		}
+		m := row.parse()
+		m.Port = name.Port
+		m.Interface = name.Interface
+		m.Unit = name.Unit
+		m.Slot = name.Slot
 
-		if idx < len(data.tempLowAlarm) {
-			m.TemperatureLowAlarm, _ = parseFloat(data.tempLowAlarm[idx])
+		if ifIndex, ok := matchIfIndex(data.ifRows, idx, name.Interface); ok {
+			applyIfRow(&m, ifIndex, data.ifRows[ifIndex])
 		}
 
-		if idx < len(data.tempHighWarning) {
-			m.TemperatureHighWarning, _ = parseFloat(data.tempHighWarning[idx])
+		if !row.hasTransceiverInfo() {
+			if e, ok := matchEntity(data.entityRows, name.Interface); ok {
+				applyEntityRow(&m, e)
+			}
 		}
 
-		if idx < len(data.tempLowWarning) {
-			m.TemperatureLowWarning, _ = parseFloat(data.tempLowWarning[idx])
-		}
+		m.Empty = row.emptyCage(&m)
 
-		// Voltage thresholds
-		if idx < len(data.voltageHighAlarm) {
-			m.VoltageHighAlarm, _ = parseFloat(data.voltageHighAlarm[idx])
-		}
+		metrics = append(metrics, m)
+	}
 
-		if idx < len(data.voltageLowAlarm) {
-			m.VoltageLowAlarm, _ = parseFloat(data.voltageLowAlarm[idx])
-		}
+	return metrics
+}
 
-		if idx < len(data.voltageHighWarning) {
-			m.VoltageHighWarning, _ = parseFloat(data.voltageHighWarning[idx])
-		}
+// emptyCage reports whether the port looks like it has no transceiver. The
+// switch keeps a row for every SFP port, reporting empty cages as zeros or
+// placeholders, with DDM unsupported and no inventory. A module with a serial
+// number, which claims DDM support, or which returns a non-zero reading is
+// taken to be present.
+func (r *ddmRow) emptyCage(m *DDMMetrics) bool {
+	if m.Vendor != "" || m.PartNumber != "" || m.Serial != "" {
+		return false
+	}
 
-		if idx < len(data.voltageLowWarning) {
-			m.VoltageLowWarning, _ = parseFloat(data.voltageLowWarning[idx])
-		}
+	if r.ddmSupported != "" && m.DDMSupported {
+		return false
+	}
 
-		// Bias Current thresholds
-		if idx < len(data.biasCurrentHighAlarm) {
-			m.BiasCurrentHighAlarm, _ = parseFloat(data.biasCurrentHighAlarm[idx])
-		}
+	readings := map[string]float64{
+		FieldTemperature: m.Temperature,
+		FieldVoltage:     m.Voltage,
+		FieldBiasCurrent: m.BiasCurrent,
+		FieldTxPower:     m.TxPower,
+		FieldRxPower:     m.RxPower,
+	}
 
-		if idx < len(data.biasCurrentLowAlarm) {
-			m.BiasCurrentLowAlarm, _ = parseFloat(data.biasCurrentLowAlarm[idx])
+	for field, v := range readings {
+		if m.Valid(field) && v != 0 {
+			return false
 		}
+	}
 
-		if idx < len(data.biasCurrentHighWarning) {
-			m.BiasCurrentHighWarning, _ = parseFloat(data.biasCurrentHighWarning[idx])
-		}
+	return true
+}
 
-		if idx < len(data.biasCurrentLowWarning) {
-			m.BiasCurrentLowWarning, _ = parseFloat(data.biasCurrentLowWarning[idx])
-		}
+// parse converts the row's raw values to DDMMetrics. Readings which are
+// missing or fail to parse are left as zero and marked invalid.
+func (r *ddmRow) parse() DDMMetrics {
+	m := DDMMetrics{
+		LAGMembership: r.lagMembership,
+		Vendor:        cleanInventoryString(r.vendor),
+		PartNumber:    cleanInventoryString(r.partNumber),
+		Serial:        cleanInventoryString(r.serial),
+		Revision:      cleanInventoryString(r.revision),
+		Connector:     cleanInventoryString(r.connector),
+		MediaType:     cleanInventoryString(r.mediaType),
+	}
 
-		// TX Power thresholds
-		if idx < len(data.txPowerHighAlarm) {
-			m.TxPowerHighAlarm, _ = parseFloat(data.txPowerHighAlarm[idx])
-		}
+	m.Wavelength, _ = parseWavelength(r.wavelength)
 
-		if idx < len(data.txPowerLowAlarm) {
-			m.TxPowerLowAlarm, _ = parseFloat(data.txPowerLowAlarm[idx])
-		}
+	// Current values
+	m.Temperature = m.parseReading(FieldTemperature, r.temp)
+	m.Voltage = m.parseReading(FieldVoltage, r.voltage)
+	m.BiasCurrent = m.parseReading(FieldBiasCurrent, r.biasCurrent)
+	m.TxPower = m.parseReading(FieldTxPower, r.txPower)
+	m.RxPower = m.parseReading(FieldRxPower, r.rxPower)
 
-		if idx < len(data.txPowerHighWarning) {
-			m.TxPowerHighWarning, _ = parseFloat(data.txPowerHighWarning[idx])
-		}
+	// Configuration - DDM config uses 0=disable, 1=enable (same as boolean)
+	m.DDMEnabled, _ = strconv.ParseBool(r.ddmEnabled)
+	m.ShutdownPolicy, _ = strconv.Atoi(r.shutdownPolicy)
 
-		if idx < len(data.txPowerLowWarning) {
-			m.TxPowerLowWarning, _ = parseFloat(data.txPowerLowWarning[idx])
-		}
+	// Status flags
+	m.DDMSupported, _ = strconv.ParseBool(r.ddmSupported)
+	m.LossOfSignal, _ = strconv.ParseBool(r.lossOfSignal)
+	m.TxFault, _ = strconv.ParseBool(r.txFault)
 
-		// RX Power thresholds
-		if idx < len(data.rxPowerHighAlarm) {
-			m.RxPowerHighAlarm, _ = parseFloat(data.rxPowerHighAlarm[idx])
-		}
+	// Temperature thresholds
+	m.TemperatureHighAlarm = m.parseReading(thresholdField(FieldTemperature, "high", "alarm"), r.tempHighAlarm)
+	m.TemperatureLowAlarm = m.parseReading(thresholdField(FieldTemperature, "low", "alarm"), r.tempLowAlarm)
+	m.TemperatureHighWarning = m.parseReading(thresholdField(FieldTemperature, "high", "warning"), r.tempHighWarning)
+	m.TemperatureLowWarning = m.parseReading(thresholdField(FieldTemperature, "low", "warning"), r.tempLowWarning)
 
-		if idx < len(data.rxPowerLowAlarm) {
-			m.RxPowerLowAlarm, _ = parseFloat(data.rxPowerLowAlarm[idx])
-		}
+	// Voltage thresholds
+	m.VoltageHighAlarm = m.parseReading(thresholdField(FieldVoltage, "high", "alarm"), r.voltageHighAlarm)
+	m.VoltageLowAlarm = m.parseReading(thresholdField(FieldVoltage, "low", "alarm"), r.voltageLowAlarm)
+	m.VoltageHighWarning = m.parseReading(thresholdField(FieldVoltage, "high", "warning"), r.voltageHighWarning)
+	m.VoltageLowWarning = m.parseReading(thresholdField(FieldVoltage, "low", "warning"), r.voltageLowWarning)
 
-		if idx < len(data.rxPowerHighWarning) {
-			m.RxPowerHighWarning, _ = parseFloat(data.rxPowerHighWarning[idx])
-		}
+	// Bias Current thresholds
+	m.BiasCurrentHighAlarm = m.parseReading(thresholdField(FieldBiasCurrent, "high", "alarm"), r.biasCurrentHighAlarm)
+	m.BiasCurrentLowAlarm = m.parseReading(thresholdField(FieldBiasCurrent, "low", "alarm"), r.biasCurrentLowAlarm)
+	m.BiasCurrentHighWarning = m.parseReading(thresholdField(FieldBiasCurrent, "high", "warning"), r.biasCurrentHighWarning)
+	m.BiasCurrentLowWarning = m.parseReading(thresholdField(FieldBiasCurrent, "low", "warning"), r.biasCurrentLowWarning)
 
-		if idx < len(data.rxPowerLowWarning) {
-			m.RxPowerLowWarning, _ = parseFloat(data.rxPowerLowWarning[idx])
-		}
+	// TX Power thresholds
+	m.TxPowerHighAlarm = m.parseReading(thresholdField(FieldTxPower, "high", "alarm"), r.txPowerHighAlarm)
+	m.TxPowerLowAlarm = m.parseReading(thresholdField(FieldTxPower, "low", "alarm"), r.txPowerLowAlarm)
+	m.TxPowerHighWarning = m.parseReading(thresholdField(FieldTxPower, "high", "warning"), r.txPowerHighWarning)
+	m.TxPowerLowWarning = m.parseReading(thresholdField(FieldTxPower, "low", "warning"), r.txPowerLowWarning)
 
-		metrics = append(metrics, m)
-	}
+	// RX Power thresholds
+	m.RxPowerHighAlarm = m.parseReading(thresholdField(FieldRxPower, "high", "alarm"), r.rxPowerHighAlarm)
+	m.RxPowerLowAlarm = m.parseReading(thresholdField(FieldRxPower, "low", "alarm"), r.rxPowerLowAlarm)
+	m.RxPowerHighWarning = m.parseReading(thresholdField(FieldRxPower, "high", "warning"), r.rxPowerHighWarning)
+	m.RxPowerLowWarning = m.parseReading(thresholdField(FieldRxPower, "low", "warning"), r.rxPowerLowWarning)
 
-	return metrics
+	return m
 }
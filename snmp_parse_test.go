@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/gosnmp/gosnmp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -19,66 +20,61 @@ func TestParseDDMMetrics(t *testing.T) {
 		{
 			name: "valid data",
 			data: &ddmWalkData{
-				sysName:      "Test Switch",
-				ports:        []string{"1/0/1", "1/0/2"},
-				temps:        []string{"45.5", "46.0"},
-				voltages:     []string{"3.30", "3.29"},
-				biasCurrents: []string{"6.0", "5.8"},
-				txPowers:     []string{"0.5", "0.6"},
-				rxPowers:     []string{"0.4", "0.45"},
+				sysName: "Test Switch",
+				rows: map[string]*ddmRow{
+					"49153": {port: "1/0/1", temp: "45.5", voltage: "3.30", biasCurrent: "6.0", txPower: "0.5", rxPower: "0.4"},
+					"49154": {port: "1/0/2", temp: "46.0", voltage: "3.29", biasCurrent: "5.8", txPower: "0.6", rxPower: "0.45"},
+				},
 			},
 			want: 2,
 		},
 		{
-			name: "mismatched lengths",
+			name: "missing columns",
 			data: &ddmWalkData{
-				sysName:      "Test Switch",
-				ports:        []string{"1/0/1", "1/0/2", "1/0/3"},
-				temps:        []string{"45.5", "46.0"}, // shorter
-				voltages:     []string{"3.30"},         // even shorter
-				biasCurrents: []string{"6.0", "5.8", "5.9"},
-				txPowers:     []string{"0.5"},
-				rxPowers:     []string{"0.4", "0.45", "0.43"},
+				sysName: "Test Switch",
+				rows: map[string]*ddmRow{
+					"49153": {port: "1/0/1", temp: "45.5", voltage: "3.30", biasCurrent: "6.0", txPower: "0.5", rxPower: "0.4"},
+					"49154": {port: "1/0/2", temp: "46.0", biasCurrent: "5.8", rxPower: "0.45"},
+					"49155": {port: "1/0/3", biasCurrent: "5.9", rxPower: "0.43"},
+				},
 			},
 			want: 3, // should handle all ports
 		},
 		{
 			name: "invalid port format",
 			data: &ddmWalkData{
-				sysName:      "Test Switch",
-				ports:        []string{"invalid", "1/0/2"},
-				temps:        []string{"45.5", "46.0"},
-				voltages:     []string{"3.30", "3.29"},
-				biasCurrents: []string{"6.0", "5.8"},
-				txPowers:     []string{"0.5", "0.6"},
-				rxPowers:     []string{"0.4", "0.45"},
+				sysName: "Test Switch",
+				rows: map[string]*ddmRow{
+					"49153": {port: "invalid", temp: "45.5"},
+					"49154": {port: "1/0/2", temp: "46.0"},
+				},
 			},
 			want: 1, // invalid port should be skipped
 		},
 		{
-			name: "invalid float values",
+			name: "row without port",
 			data: &ddmWalkData{
-				sysName:      "Test Switch",
-				ports:        []string{"1/0/1"},
-				temps:        []string{"invalid"},
-				voltages:     []string{"not-a-number"},
-				biasCurrents: []string{"bad"},
-				txPowers:     []string{"wrong"},
-				rxPowers:     []string{"nope"},
+				sysName: "Test Switch",
+				rows: map[string]*ddmRow{
+					"49153": {ddmEnabled: "1"},
+					"49154": {port: "1/0/2", temp: "46.0"},
+				},
 			},
-			want: 1, // should still create metric with zero values
+			want: 1, // rows only present in other tables should be skipped
 		},
 		{
-			name: "empty data",
+			name: "invalid float values",
 			data: &ddmWalkData{
-				sysName:      "Test Switch",
-				ports:        []string{},
-				temps:        []string{},
-				voltages:     []string{},
-				biasCurrents: []string{},
-				txPowers:     []string{},
-				rxPowers:     []string{},
+				sysName: "Test Switch",
+				rows: map[string]*ddmRow{
+					"49153": {port: "1/0/1", temp: "invalid", voltage: "not-a-number", biasCurrent: "bad", txPower: "wrong", rxPower: "nope"},
+				},
 			},
+			want: 1, // should still create metric, with the readings marked invalid
+		},
+		{
+			name: "empty data",
+			data: &ddmWalkData{sysName: "Test Switch"},
 			want: 0,
 		},
 	}
@@ -101,13 +97,10 @@ func TestParseDDMMetrics_Values(t *testing.T) {
 	client := &SNMPClient{}
 
 	data := &ddmWalkData{
-		sysName:      "Test Switch",
-		ports:        []string{"1/0/1"},
-		temps:        []string{"45.5"},
-		voltages:     []string{"3.30"},
-		biasCurrents: []string{"6.0"},
-		txPowers:     []string{"0.5"},
-		rxPowers:     []string{"0.4"},
+		sysName: "Test Switch",
+		rows: map[string]*ddmRow{
+			"49153": {port: "1/0/1", temp: "45.5", voltage: "3.30", biasCurrent: "6.0", txPower: "0.5", rxPower: "0.4"},
+		},
 	}
 
 	metrics := client.parseDDMMetrics(context.Background(), data)
@@ -124,44 +117,151 @@ func TestParseDDMMetrics_Values(t *testing.T) {
 	assert.InDelta(t, 0.4, m.RxPower, 0.01)
 }
 
+func TestParseDDMMetrics_RowOrder(t *testing.T) {
+	client := &SNMPClient{}
+
+	data := &ddmWalkData{
+		rows: map[string]*ddmRow{
+			"49162": {port: "1/0/10"},
+			"49153": {port: "1/0/1"},
+			"49161": {port: "1/0/9"},
+		},
+	}
+
+	metrics := client.parseDDMMetrics(context.Background(), data)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, "1", metrics[0].Port)
+	assert.Equal(t, "9", metrics[1].Port)
+	assert.Equal(t, "10", metrics[2].Port)
+}
+
+// TestParseDDMMetrics_SparseTable checks that values are joined by row index,
+// so a hole in one column doesn't shift values onto the wrong port.
+func TestParseDDMMetrics_SparseTable(t *testing.T) {
+	dispatch := buildOIDDispatch()
+	data := &ddmWalkData{}
+
+	str := func(oid, idx, val string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: "." + oid + "." + idx, Type: gosnmp.OctetString, Value: []byte(val)}
+	}
+
+	// port 2 (49154) has no temperature reading and no threshold entries,
+	// port 3 (49155) has no RX power
+	pdus := []gosnmp.SnmpPDU{
+		str(oidDDMStatusPort, "49153", "1/0/1"),
+		str(oidDDMStatusPort, "49154", "1/0/2"),
+		str(oidDDMStatusPort, "49155", "1/0/3"),
+		str(oidDDMStatusTemperature, "49153", "41.0"),
+		str(oidDDMStatusTemperature, "49155", "43.0"),
+		str(oidDDMStatusRxPower, "49153", "-3.1"),
+		str(oidDDMStatusRxPower, "49154", "-3.2"),
+		str(oidDDMTemperatureHighAlarm, "49153", "80.0"),
+		str(oidDDMTemperatureHighAlarm, "49155", "85.0"),
+		str(oidDDMRxPowerLowAlarm, "49153", "-20.0"),
+		str(oidDDMRxPowerLowAlarm, "49155", "-25.0"),
+		{Name: "." + oidDDMConfigStatus + ".49155", Type: gosnmp.Integer, Value: 1},
+	}
+
+	for _, pdu := range pdus {
+		dispatchPDU(pdu, dispatch, data)
+	}
+
+	metrics := (&SNMPClient{}).parseDDMMetrics(context.Background(), data)
+	require.Len(t, metrics, 3)
+
+	p1, p2, p3 := metrics[0], metrics[1], metrics[2]
+
+	assert.Equal(t, "1", p1.Port)
+	assert.InDelta(t, 41.0, p1.Temperature, 0.01)
+	assert.InDelta(t, -3.1, p1.RxPower, 0.01)
+	assert.InDelta(t, 80.0, p1.TemperatureHighAlarm, 0.01)
+	assert.InDelta(t, -20.0, p1.RxPowerLowAlarm, 0.01)
+	assert.False(t, p1.DDMEnabled)
+
+	assert.Equal(t, "2", p2.Port)
+	assert.Zero(t, p2.Temperature)
+	assert.False(t, p2.Valid(FieldTemperature))
+	assert.False(t, p2.Valid(thresholdField(FieldTemperature, "high", "alarm")))
+	assert.True(t, p2.Valid(FieldRxPower))
+	assert.Empty(t, p2.ParseErrors)
+	assert.InDelta(t, -3.2, p2.RxPower, 0.01)
+	assert.Zero(t, p2.TemperatureHighAlarm)
+	assert.Zero(t, p2.RxPowerLowAlarm)
+	assert.False(t, p2.DDMEnabled)
+
+	assert.Equal(t, "3", p3.Port)
+	assert.InDelta(t, 43.0, p3.Temperature, 0.01)
+	assert.Zero(t, p3.RxPower)
+	assert.InDelta(t, 85.0, p3.TemperatureHighAlarm, 0.01)
+	assert.InDelta(t, -25.0, p3.RxPowerLowAlarm, 0.01)
+	assert.True(t, p3.DDMEnabled)
+}
+
+func TestParseDDMMetrics_InvalidReadings(t *testing.T) {
+	data := &ddmWalkData{
+		rows: map[string]*ddmRow{
+			"49153": {port: "1/0/1", temp: "45.5", voltage: "N/A", txPower: "--", rxPower: "garbage", tempHighAlarm: "??"},
+		},
+	}
+
+	metrics := (&SNMPClient{}).parseDDMMetrics(context.Background(), data)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.True(t, m.Valid(FieldTemperature))
+	assert.False(t, m.Valid(FieldVoltage))
+	assert.False(t, m.Valid(FieldBiasCurrent))
+	assert.False(t, m.Valid(FieldTxPower))
+	assert.False(t, m.Valid(FieldRxPower))
+	assert.False(t, m.Valid(thresholdField(FieldTemperature, "high", "alarm")))
+
+	// only values which weren't placeholders or missing are parse errors
+	assert.ElementsMatch(t, []string{FieldRxPower, "temperature_high_alarm"}, m.ParseErrors)
+}
+
 func TestParseDDMMetrics_OptionalFields(t *testing.T) {
 	client := &SNMPClient{}
 
 	t.Run("with all optional fields", func(t *testing.T) {
 		data := &ddmWalkData{
-			sysName:                "Test Switch",
-			ports:                  []string{"1/0/1"},
-			temps:                  []string{"45.5"},
-			voltages:               []string{"3.30"},
-			biasCurrents:           []string{"6.0"},
-			txPowers:               []string{"0.5"},
-			rxPowers:               []string{"0.4"},
-			ddmEnabled:             []string{"1"},
-			shutdownPolicy:         []string{"2"},
-			lagMembership:          []string{"Trunk1"},
-			ddmSupported:           []string{"1"},
-			lossOfSignal:           []string{"0"},
-			txFault:                []string{"0"},
-			tempHighAlarm:          []string{"80.0"},
-			tempLowAlarm:           []string{"-10.0"},
-			tempHighWarning:        []string{"70.0"},
-			tempLowWarning:         []string{"0.0"},
-			voltageHighAlarm:       []string{"3.6"},
-			voltageLowAlarm:        []string{"2.9"},
-			voltageHighWarning:     []string{"3.5"},
-			voltageLowWarning:      []string{"3.0"},
-			biasCurrentHighAlarm:   []string{"85.0"},
-			biasCurrentLowAlarm:    []string{"1.0"},
-			biasCurrentHighWarning: []string{"70.0"},
-			biasCurrentLowWarning:  []string{"2.0"},
-			txPowerHighAlarm:       []string{"1.0"},
-			txPowerLowAlarm:        []string{"-5.0"},
-			txPowerHighWarning:     []string{"0.5"},
-			txPowerLowWarning:      []string{"-4.0"},
-			rxPowerHighAlarm:       []string{"1.0"},
-			rxPowerLowAlarm:        []string{"-20.0"},
-			rxPowerHighWarning:     []string{"0.5"},
-			rxPowerLowWarning:      []string{"-18.0"},
+			sysName: "Test Switch",
+			rows: map[string]*ddmRow{
+				"49153": {
+					port:                   "1/0/1",
+					temp:                   "45.5",
+					voltage:                "3.30",
+					biasCurrent:            "6.0",
+					txPower:                "0.5",
+					rxPower:                "0.4",
+					ddmEnabled:             "1",
+					shutdownPolicy:         "2",
+					lagMembership:          "Trunk1",
+					ddmSupported:           "1",
+					lossOfSignal:           "0",
+					txFault:                "0",
+					tempHighAlarm:          "80.0",
+					tempLowAlarm:           "-10.0",
+					tempHighWarning:        "70.0",
+					tempLowWarning:         "0.0",
+					voltageHighAlarm:       "3.6",
+					voltageLowAlarm:        "2.9",
+					voltageHighWarning:     "3.5",
+					voltageLowWarning:      "3.0",
+					biasCurrentHighAlarm:   "85.0",
+					biasCurrentLowAlarm:    "1.0",
+					biasCurrentHighWarning: "70.0",
+					biasCurrentLowWarning:  "2.0",
+					txPowerHighAlarm:       "1.0",
+					txPowerLowAlarm:        "-5.0",
+					txPowerHighWarning:     "0.5",
+					txPowerLowWarning:      "-4.0",
+					rxPowerHighAlarm:       "1.0",
+					rxPowerLowAlarm:        "-20.0",
+					rxPowerHighWarning:     "0.5",
+					rxPowerLowWarning:      "-18.0",
+				},
+			},
 		}
 
 		metrics := client.parseDDMMetrics(context.Background(), data)
@@ -184,13 +284,10 @@ func TestParseDDMMetrics_OptionalFields(t *testing.T) {
 
 	t.Run("with nil optional fields", func(t *testing.T) {
 		data := &ddmWalkData{
-			sysName:      "Test Switch",
-			ports:        []string{"1/0/1"},
-			temps:        []string{"45.5"},
-			voltages:     []string{"3.30"},
-			biasCurrents: []string{"6.0"},
-			txPowers:     []string{"0.5"},
-			rxPowers:     []string{"0.4"},
+			sysName: "Test Switch",
+			rows: map[string]*ddmRow{
+				"49153": {port: "1/0/1", temp: "45.5", voltage: "3.30", biasCurrent: "6.0", txPower: "0.5", rxPower: "0.4"},
+			},
 		}
 
 		metrics := client.parseDDMMetrics(context.Background(), data)
@@ -205,3 +302,29 @@ func TestParseDDMMetrics_OptionalFields(t *testing.T) {
 		assert.InDelta(t, 0, m.TemperatureHighAlarm, 0.01)
 	})
 }
+
+func TestParseDDMMetrics_EmptyCage(t *testing.T) {
+	data := &ddmWalkData{
+		rows: map[string]*ddmRow{
+			// empty cage: DDM unsupported, zeros and placeholders
+			"49153": {port: "1/0/1", ddmSupported: "0", temp: "0.0", voltage: "0.00", biasCurrent: "--", txPower: "--", rxPower: "--"},
+			// module without DDM, identified by its inventory
+			"49154": {port: "1/0/2", ddmSupported: "0", vendor: "FS", serial: "S1"},
+			// DDM-capable module
+			"49155": {port: "1/0/3", ddmSupported: "1", temp: "0.0"},
+			// no status columns, but live readings
+			"49156": {port: "1/0/4", temp: "41.0", rxPower: "-3.1"},
+			// no status columns and no readings
+			"49157": {port: "1/0/5"},
+		},
+	}
+
+	metrics := (&SNMPClient{}).parseDDMMetrics(context.Background(), data)
+	require.Len(t, metrics, 5)
+
+	assert.True(t, metrics[0].Empty)
+	assert.False(t, metrics[1].Empty)
+	assert.False(t, metrics[2].Empty)
+	assert.False(t, metrics[3].Empty)
+	assert.True(t, metrics[4].Empty)
+}
@@ -2,6 +2,7 @@ package tplinkddm
 
 import (
 	"testing"
+	"time"
 )
 
 func TestNewSNMPClient(t *testing.T) {
@@ -17,8 +18,12 @@ func TestNewSNMPClient(t *testing.T) {
 		t.Errorf("target = %v, want 192.168.1.1", client.target)
 	}
 
-	if client.community != "public" {
-		t.Errorf("community = %v, want public", client.community)
+	if client.auth.Community != "public" {
+		t.Errorf("community = %v, want public", client.auth.Community)
+	}
+
+	if client.auth.Version != 2 {
+		t.Errorf("version = %v, want 2", client.auth.Version)
 	}
 }
 
@@ -59,3 +64,31 @@ func TestOIDs(t *testing.T) {
 		t.Errorf("oidDDMStatusRxPower = %v, want %v", oidDDMStatusRxPower, expectedOIDs["rxPower"])
 	}
 }
+
+func TestFitTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		retries   int
+		remaining time.Duration
+		want      time.Duration
+	}{
+		{"fits", 2 * time.Second, 1, 10 * time.Second, 2 * time.Second},
+		{"exactly fits", 2 * time.Second, 1, 4 * time.Second, 2 * time.Second},
+		{"shortened", 2 * time.Second, 1, 3 * time.Second, 1500 * time.Millisecond},
+		{"no retries", 5 * time.Second, 0, 2 * time.Second, 2 * time.Second},
+		{"deadline passed", 2 * time.Second, 1, -time.Second, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := fitTimeout(tt.timeout, tt.retries, tt.remaining); got != tt.want {
+				t.Errorf("fitTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package tplinkddm
 
 import (
+	"context"
 	"testing"
 
 	"github.com/gosnmp/gosnmp"
@@ -27,6 +28,12 @@ func TestPduToString(t *testing.T) {
 			want: "1",
 			ok:   true,
 		},
+		{
+			name: "Gauge32",
+			pdu:  gosnmp.SnmpPDU{Type: gosnmp.Gauge32, Value: uint(10000)},
+			want: "10000",
+			ok:   true,
+		},
 		{
 			name: "unsupported type",
 			pdu:  gosnmp.SnmpPDU{Type: gosnmp.Counter32, Value: uint(42)},
@@ -47,8 +54,7 @@ func TestPduToString(t *testing.T) {
 }
 
 func TestBuildOIDDispatch(t *testing.T) {
-	data := &ddmWalkData{}
-	dispatch := buildOIDDispatch(data)
+	dispatch := buildOIDDispatch()
 
 	assert.Contains(t, dispatch, oidDDMStatusPort)
 	assert.Contains(t, dispatch, oidDDMStatusTemperature)
@@ -56,12 +62,22 @@ func TestBuildOIDDispatch(t *testing.T) {
 	assert.Contains(t, dispatch, oidDDMTemperatureHighAlarm)
 	assert.Contains(t, dispatch, oidDDMRxPowerLowWarning)
 
-	assert.Len(t, dispatch, 32)
+	assert.Len(t, dispatch, 39)
+
+	// every column must map to a distinct field
+	row := &ddmRow{}
+	fields := map[*string]bool{}
+
+	for _, field := range dispatch {
+		fields[field(row)] = true
+	}
+
+	assert.Len(t, fields, 39)
 }
 
 func TestDispatchPDU(t *testing.T) {
 	data := &ddmWalkData{}
-	dispatch := buildOIDDispatch(data)
+	dispatch := buildOIDDispatch()
 
 	pdus := []gosnmp.SnmpPDU{
 		{Name: "." + oidDDMStatusPort + ".49153", Type: gosnmp.OctetString, Value: []byte("1/0/1")},
@@ -84,48 +100,108 @@ func TestDispatchPDU(t *testing.T) {
 	}
 
 	for _, pdu := range pdus {
-		dispatchPDU(pdu, dispatch)
+		dispatchPDU(pdu, dispatch, data)
 	}
 
-	require.Equal(t, []string{"1/0/1", "1/0/2"}, data.ports)
-	require.Equal(t, []string{"45.5", "46.0"}, data.temps)
-	require.Equal(t, []string{"3.30", "3.29"}, data.voltages)
-	require.Equal(t, []string{"6.0"}, data.biasCurrents)
-	require.Equal(t, []string{"0.5"}, data.txPowers)
-	require.Equal(t, []string{"0.4"}, data.rxPowers)
-	require.Equal(t, []string{"1"}, data.ddmEnabled)
-	require.Equal(t, []string{"2"}, data.shutdownPolicy)
-	require.Equal(t, []string{"N/A"}, data.lagMembership)
-	require.Equal(t, []string{"1"}, data.ddmSupported)
-	require.Equal(t, []string{"0"}, data.lossOfSignal)
-	require.Equal(t, []string{"0"}, data.txFault)
-	require.Equal(t, []string{"80.0"}, data.tempHighAlarm)
-	require.Equal(t, []string{"-18.0"}, data.rxPowerLowWarning)
+	require.Len(t, data.rows, 2)
+	assert.Equal(t, []string{"49153", "49154"}, data.indexes())
+
+	assert.Equal(t, &ddmRow{
+		port:              "1/0/1",
+		temp:              "45.5",
+		voltage:           "3.30",
+		biasCurrent:       "6.0",
+		txPower:           "0.5",
+		rxPower:           "0.4",
+		ddmEnabled:        "1",
+		shutdownPolicy:    "2",
+		lagMembership:     "N/A",
+		ddmSupported:      "1",
+		lossOfSignal:      "0",
+		txFault:           "0",
+		tempHighAlarm:     "80.0",
+		rxPowerLowWarning: "-18.0",
+	}, data.rows["49153"])
+
+	assert.Equal(t, &ddmRow{
+		port:    "1/0/2",
+		temp:    "46.0",
+		voltage: "3.29",
+	}, data.rows["49154"])
+}
+
+func TestDispatchPDU_MultiComponentIndex(t *testing.T) {
+	data := &ddmWalkData{}
+	dispatch := buildOIDDispatch()
+
+	dispatchPDU(gosnmp.SnmpPDU{
+		Name: "." + oidDDMStatusPort + ".2.49153", Type: gosnmp.OctetString, Value: []byte("2/0/1"),
+	}, dispatch, data)
+	dispatchPDU(gosnmp.SnmpPDU{
+		Name: "." + oidDDMStatusTemperature + ".2.49153", Type: gosnmp.OctetString, Value: []byte("40.0"),
+	}, dispatch, data)
+
+	require.Contains(t, data.rows, "2.49153")
+	assert.Equal(t, "2/0/1", data.rows["2.49153"].port)
+	assert.Equal(t, "40.0", data.rows["2.49153"].temp)
 }
 
 func TestDispatchPDU_IgnoresUnknownOIDs(t *testing.T) {
 	data := &ddmWalkData{}
-	dispatch := buildOIDDispatch(data)
+	dispatch := buildOIDDispatch()
 
 	dispatchPDU(gosnmp.SnmpPDU{
 		Name:  ".1.3.6.1.4.1.11863.6.96.1.99.1.1.1.49153",
 		Type:  gosnmp.OctetString,
 		Value: []byte("unknown"),
-	}, dispatch)
+	}, dispatch, data)
 
-	assert.Empty(t, data.ports)
-	assert.Empty(t, data.temps)
+	assert.Empty(t, data.rows)
 }
 
 func TestDispatchPDU_IgnoresUnsupportedTypes(t *testing.T) {
 	data := &ddmWalkData{}
-	dispatch := buildOIDDispatch(data)
+	dispatch := buildOIDDispatch()
 
 	dispatchPDU(gosnmp.SnmpPDU{
 		Name:  "." + oidDDMStatusPort + ".49153",
 		Type:  gosnmp.Counter32,
 		Value: uint(42),
-	}, dispatch)
+	}, dispatch, data)
+
+	assert.Empty(t, data.rows)
+}
+
+func TestCompareOIDs(t *testing.T) {
+	assert.Negative(t, compareOIDs("49153", "49154"))
+	assert.Negative(t, compareOIDs("9", "10"))
+	assert.Negative(t, compareOIDs(".1.3.6.1", ".1.3.6.1.2"))
+	assert.Positive(t, compareOIDs("2.1", "1.49153"))
+	assert.Zero(t, compareOIDs("1.2.3", ".1.2.3"))
+}
+
+func TestGetDDMMetrics_Errors(t *testing.T) {
+	t.Run("no data", func(t *testing.T) {
+		agent := newTestAgent(t, "public", []gosnmp.SnmpPDU{
+			{Name: "." + oidSysName, Type: gosnmp.OctetString, Value: []byte("empty-switch")},
+		})
+
+		client := NewSNMPClient("127.0.0.1", "public")
+		client.port = agent.port
+
+		_, err := client.GetDDMMetrics(context.Background())
+		require.ErrorIs(t, err, ErrNoData)
+	})
+
+	t.Run("unparseable ports", func(t *testing.T) {
+		agent := newTestAgent(t, "public", []gosnmp.SnmpPDU{
+			{Name: "." + oidDDMStatusPort + ".49153", Type: gosnmp.OctetString, Value: []byte("bogus")},
+		})
+
+		client := NewSNMPClient("127.0.0.1", "public")
+		client.port = agent.port
 
-	assert.Empty(t, data.ports)
+		_, err := client.GetDDMMetrics(context.Background())
+		require.ErrorIs(t, err, ErrParse)
+	})
 }
@@ -0,0 +1,240 @@
+package tplinkddm
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+const testEngineID = "\x80\x00\x2e\x57\x03\x00\x11\x22\x33\x44\x55"
+
+// testAgent is a minimal SNMP agent stand-in serving a fixed set of variables
+// over UDP on loopback. It answers Get, GetNext and GetBulk for v2c, and for
+// v3 it also handles engine discovery and USM authentication/privacy.
+type testAgent struct {
+	conn   *net.UDPConn
+	params *gosnmp.GoSNMP
+	vars   []gosnmp.SnmpPDU
+	port   uint16
+
+	mu       sync.Mutex
+	requests int
+}
+
+// newTestAgent starts a v2c agent accepting the given community.
+func newTestAgent(t *testing.T, community string, vars []gosnmp.SnmpPDU) *testAgent {
+	t.Helper()
+
+	return startTestAgent(t, &gosnmp.GoSNMP{
+		Version:   gosnmp.Version2c,
+		Community: community,
+		Logger:    gosnmp.NewLogger(nil),
+	}, vars)
+}
+
+// newTestAgentV3 starts a v3 agent accepting the given USM credentials.
+func newTestAgentV3(t *testing.T, auth Auth, vars []gosnmp.SnmpPDU) *testAgent {
+	t.Helper()
+
+	params := &gosnmp.GoSNMP{Logger: gosnmp.NewLogger(nil)}
+	require.NoError(t, auth.configure(params))
+
+	usm, ok := params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	require.True(t, ok)
+
+	usm.AuthoritativeEngineID = testEngineID
+	usm.AuthoritativeEngineBoots = 1
+	usm.AuthoritativeEngineTime = 1
+	require.NoError(t, usm.InitSecurityKeys())
+
+	return startTestAgent(t, params, vars)
+}
+
+func startTestAgent(t *testing.T, params *gosnmp.GoSNMP, vars []gosnmp.SnmpPDU) *testAgent {
+	t.Helper()
+
+	return listenTestAgent(t, net.IPv4(127, 0, 0, 1), params, vars)
+}
+
+// listenTestAgent starts an agent listening on the given loopback IP
+func listenTestAgent(t *testing.T, ip net.IP, params *gosnmp.GoSNMP, vars []gosnmp.SnmpPDU) *testAgent {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+	if err != nil && ip.To4() == nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+
+	require.NoError(t, err)
+
+	sorted := slices.Clone(vars)
+	slices.SortFunc(sorted, func(a, b gosnmp.SnmpPDU) int {
+		return compareOIDs(a.Name, b.Name)
+	})
+
+	a := &testAgent{
+		conn:   conn,
+		params: params,
+		vars:   sorted,
+		port:   uint16(conn.LocalAddr().(*net.UDPAddr).Port), //nolint:gosec // port is always in range
+	}
+
+	go a.serve()
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return a
+}
+
+// requestCount returns the number of requests answered so far
+func (a *testAgent) requestCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.requests
+}
+
+func (a *testAgent) serve() {
+	buf := make([]byte, 65535)
+
+	for {
+		n, remote, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		req, err := a.params.UnmarshalTrap(slices.Clone(buf[:n]), false)
+		if err != nil {
+			continue
+		}
+
+		// like a real agent, silently drop requests with the wrong community
+		if req.Version != gosnmp.Version3 && req.Community != a.params.Community {
+			continue
+		}
+
+		resp := a.respond(req)
+
+		out, err := resp.MarshalMsg()
+		if err != nil {
+			continue
+		}
+
+		_, _ = a.conn.WriteToUDP(out, remote)
+	}
+}
+
+func (a *testAgent) respond(req *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	resp := &gosnmp.SnmpPacket{
+		Version:        req.Version,
+		Community:      req.Community,
+		PDUType:        gosnmp.GetResponse,
+		RequestID:      req.RequestID,
+		MsgID:          req.MsgID,
+		SecurityModel:  req.SecurityModel,
+		MsgFlags:       req.MsgFlags &^ gosnmp.Reportable,
+		ContextName:    req.ContextName,
+		MaxRepetitions: req.MaxRepetitions,
+		Logger:         gosnmp.NewLogger(nil),
+	}
+
+	if req.Version == gosnmp.Version3 {
+		usm := a.params.SecurityParameters.Copy().(*gosnmp.UsmSecurityParameters) //nolint:forcetypeassert // always USM
+		usm.PrivacyParameters = []byte{0, 0, 0, 0, 0, 0, 0, 1}
+		resp.SecurityParameters = usm
+		resp.ContextEngineID = testEngineID
+
+		reqUSM, _ := req.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if reqUSM == nil || reqUSM.AuthoritativeEngineID != testEngineID {
+			// engine discovery: report our engine ID, boots and time
+			resp.PDUType = gosnmp.Report
+			resp.MsgFlags = gosnmp.NoAuthNoPriv
+			resp.Variables = []gosnmp.SnmpPDU{
+				{Name: ".1.3.6.1.6.3.15.1.1.4.0", Type: gosnmp.Counter32, Value: uint32(1)},
+			}
+
+			return resp
+		}
+	}
+
+	a.mu.Lock()
+	a.requests++
+	a.mu.Unlock()
+
+	//nolint:exhaustive // only read requests are served
+	switch req.PDUType {
+	case gosnmp.GetRequest:
+		for _, v := range req.Variables {
+			resp.Variables = append(resp.Variables, a.get(v.Name))
+		}
+	case gosnmp.GetNextRequest:
+		for _, v := range req.Variables {
+			resp.Variables = append(resp.Variables, a.next(v.Name, 1)...)
+		}
+	case gosnmp.GetBulkRequest:
+		for _, v := range req.Variables {
+			resp.Variables = append(resp.Variables, a.next(v.Name, int(max(req.MaxRepetitions, 1)))...)
+		}
+	}
+
+	return resp
+}
+
+func (a *testAgent) get(oid string) gosnmp.SnmpPDU {
+	for _, v := range a.vars {
+		if v.Name == oid {
+			return v
+		}
+	}
+
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.NoSuchObject}
+}
+
+func (a *testAgent) next(oid string, n int) []gosnmp.SnmpPDU {
+	out := []gosnmp.SnmpPDU{}
+
+	for _, v := range a.vars {
+		if compareOIDs(v.Name, oid) > 0 {
+			out = append(out, v)
+			if len(out) == n {
+				break
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		out = append(out, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
+	}
+
+	return out
+}
+
+// testDDMVars returns a minimal DDM table with two ports, plus sysName
+func testDDMVars() []gosnmp.SnmpPDU {
+	str := func(oid, idx, val string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: "." + oid + "." + idx, Type: gosnmp.OctetString, Value: []byte(val)}
+	}
+
+	return []gosnmp.SnmpPDU{
+		{Name: "." + oidSysName, Type: gosnmp.OctetString, Value: []byte("agent-switch")},
+		str(oidDDMStatusPort, "49153", "1/0/1"),
+		str(oidDDMStatusPort, "49154", "1/0/2"),
+		str(oidDDMStatusTemperature, "49153", "45.5"),
+		str(oidDDMStatusTemperature, "49154", "46.0"),
+		str(oidDDMStatusVoltage, "49153", "3.30"),
+		str(oidDDMStatusVoltage, "49154", "3.29"),
+		str(oidDDMStatusTxPower, "49153", "-2.5"),
+		str(oidDDMStatusTxPower, "49154", "-2.6"),
+		str(oidDDMStatusRxPower, "49153", "-4.1"),
+		str(oidDDMStatusRxPower, "49154", "-4.2"),
+	}
+}
@@ -0,0 +1,154 @@
+package tplinkddm
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// System OIDs, fetched with a single Get alongside sysName
+const (
+	oidSysDescr    = "1.3.6.1.2.1.1.1.0"
+	oidSysObjectID = "1.3.6.1.2.1.1.2.0"
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0" // TimeTicks, hundredths of a second
+
+	// TP-Link private system info MIB (tpSysInfo)
+	oidTPSysInfoDescription = "1.3.6.1.4.1.11863.6.1.1.1.0" // model description
+	oidTPSysInfoHwVersion   = "1.3.6.1.4.1.11863.6.1.1.5.0"
+	oidTPSysInfoSwVersion   = "1.3.6.1.4.1.11863.6.1.1.6.0"
+)
+
+// TPLINK-SYSMONITOR-MIB (tplinkSysMonitorMIBObjects, 1.3.6.1.4.1.11863.6.4.1)
+// columns, both tables indexed by stack unit number. The MIB only defines the
+// CPU and memory tables; TP-Link's MIBs have no fan, temperature or PSU objects.
+const (
+	oidSysMonitorCPU1Minute        = "1.3.6.1.4.1.11863.6.4.1.1.1.1.3" // tpSysMonitorCpu1Minute, percent
+	oidSysMonitorMemoryUtilization = "1.3.6.1.4.1.11863.6.4.1.2.1.1.2" // tpSysMonitorMemoryUtilization, percent
+)
+
+// SystemInfo holds a switch's identity and health. CPU and Memory are keyed
+// by stack unit, and are empty if the switch doesn't expose them.
+type SystemInfo struct {
+	CPU      map[string]float64 // 1-minute CPU utilization, 0-1
+	Memory   map[string]float64 // memory utilization, 0-1
+	Descr    string
+	ObjectID string
+	Model    string
+	Firmware string
+	Hardware string
+	Uptime   time.Duration
+}
+
+// sysMonitorRow holds the raw system monitor values for one unit
+type sysMonitorRow struct {
+	cpu    string
+	memory string
+}
+
+// sysMonitorColumns maps each walked system monitor column to its field in
+// sysMonitorRow
+//
+//nolint:gochecknoglobals // lookup table
+var sysMonitorColumns = []tableColumn[sysMonitorRow]{
+	{oid: oidSysMonitorCPU1Minute, field: func(r *sysMonitorRow) *string { return &r.cpu }},
+	{oid: oidSysMonitorMemoryUtilization, field: func(r *sysMonitorRow) *string { return &r.memory }},
+}
+
+// getSystemInfo fetches sysName and the system scalars with one Get. Missing
+// values are left empty rather than failing the scrape.
+func (c *SNMPClient) getSystemInfo(ctx context.Context, client *gosnmp.GoSNMP) (string, SystemInfo) {
+	_, span := tracer.Start(ctx, "SNMPClient.getSystemInfo")
+	defer span.End()
+
+	client.Context = ctx
+
+	var (
+		name string
+		info SystemInfo
+	)
+
+	result, err := client.Get([]string{
+		oidSysName, oidSysDescr, oidSysObjectID, oidSysUpTime,
+		oidTPSysInfoDescription, oidTPSysInfoHwVersion, oidTPSysInfoSwVersion,
+	})
+	if err != nil {
+		slog.Debug("failed to get system info", "error", err)
+		span.RecordError(err)
+
+		return "", info
+	}
+
+	for _, pdu := range result.Variables {
+		//nolint:exhaustive // NoSuchObject and friends are skipped
+		switch pdu.Type {
+		case gosnmp.OctetString:
+			val := strings.TrimSpace(string(pdu.Value.([]byte)))
+
+			switch strings.TrimPrefix(pdu.Name, ".") {
+			case oidSysName:
+				name = val
+			case oidSysDescr:
+				info.Descr = val
+			case oidTPSysInfoDescription:
+				info.Model = val
+			case oidTPSysInfoHwVersion:
+				info.Hardware = val
+			case oidTPSysInfoSwVersion:
+				info.Firmware = val
+			}
+		case gosnmp.ObjectIdentifier:
+			info.ObjectID = strings.TrimPrefix(pdu.Value.(string), ".")
+		case gosnmp.TimeTicks:
+			ticks := gosnmp.ToBigInt(pdu.Value).Int64()
+			info.Uptime = time.Duration(ticks) * 10 * time.Millisecond
+		}
+	}
+
+	// switches without the private MIB still describe themselves in sysDescr
+	if info.Model == "" {
+		info.Model = info.Descr
+	}
+
+	span.SetAttributes(
+		attribute.String("snmp.sysName", name),
+		attribute.String("snmp.model", info.Model),
+	)
+
+	return name, info
+}
+
+// walkSysMonitor walks the system monitor tables into data.system
+func (c *SNMPClient) walkSysMonitor(ctx context.Context, client *gosnmp.GoSNMP, data *ddmWalkData) {
+	ctx, span := tracer.Start(ctx, "SNMPClient.walkSysMonitor")
+	defer span.End()
+
+	client.Context = ctx
+
+	rows, err := walkColumns(client, sysMonitorColumns, &data.pduCount)
+	if err != nil {
+		slog.DebugContext(ctx, "system monitor walk failed", "target", c.target, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "system monitor walk failed")
+	}
+
+	info := &data.system
+	info.CPU = map[string]float64{}
+	info.Memory = map[string]float64{}
+
+	for unit, r := range rows {
+		if v, err := parseFloat(r.cpu); err == nil {
+			info.CPU[unit] = v / 100
+		}
+
+		if v, err := parseFloat(r.memory); err == nil {
+			info.Memory[unit] = v / 100
+		}
+	}
+
+	span.SetAttributes(attribute.Int("sysmonitor.units", len(rows)))
+}
@@ -0,0 +1,92 @@
+package tplinkddm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDDMMetrics_System(t *testing.T) {
+	t.Parallel()
+
+	str := func(oid, val string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: "." + oid, Type: gosnmp.OctetString, Value: []byte(val)}
+	}
+
+	vars := append(testDDMVars(),
+		str(oidSysDescr, "JetStream 24-Port Gigabit L2+ Managed Switch with 4 10GE SFP+ Slots"),
+		gosnmp.SnmpPDU{Name: "." + oidSysObjectID, Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.11863.5.100"},
+		gosnmp.SnmpPDU{Name: "." + oidSysUpTime, Type: gosnmp.TimeTicks, Value: uint32(123456)},
+		str(oidTPSysInfoDescription, "T2600G-28SQ"),
+		str(oidTPSysInfoHwVersion, "T2600G-28SQ 3.0"),
+		str(oidTPSysInfoSwVersion, "3.0.0 Build 20230512 Rel.54136"),
+	)
+
+	// TPLINK-SYSMONITOR-MIB tables of a two-unit stack, in walk order:
+	// tpSysMonitorCpuTable (number, 5s, 1m, 5m) then tpSysMonitorMemoryTable
+	// (number, utilization)
+	for _, v := range []struct {
+		oid string
+		val int
+	}{
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.1.1", 1},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.1.2", 2},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.2.1", 12},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.2.2", 7},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.3.1", 9},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.3.2", 6},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.4.1", 8},
+		{"1.3.6.1.4.1.11863.6.4.1.1.1.1.4.2", 6},
+		{"1.3.6.1.4.1.11863.6.4.1.2.1.1.1.1", 1},
+		{"1.3.6.1.4.1.11863.6.4.1.2.1.1.1.2", 2},
+		{"1.3.6.1.4.1.11863.6.4.1.2.1.1.2.1", 35},
+		{"1.3.6.1.4.1.11863.6.4.1.2.1.1.2.2", 31},
+	} {
+		vars = append(vars, gosnmp.SnmpPDU{Name: "." + v.oid, Type: gosnmp.Integer, Value: v.val})
+	}
+
+	agent := newTestAgent(t, "public", vars)
+
+	client := NewSNMPClient("127.0.0.1", "public")
+	client.port = agent.port
+
+	result, err := client.GetDDMMetrics(context.Background())
+	require.NoError(t, err)
+
+	sys := result.System
+	assert.Equal(t, "agent-switch", result.SysName)
+	assert.Equal(t, "T2600G-28SQ", sys.Model)
+	assert.Equal(t, "T2600G-28SQ 3.0", sys.Hardware)
+	assert.Equal(t, "3.0.0 Build 20230512 Rel.54136", sys.Firmware)
+	assert.Equal(t, "1.3.6.1.4.1.11863.5.100", sys.ObjectID)
+	assert.Equal(t, 1234560*time.Millisecond, sys.Uptime)
+	assert.Equal(t, map[string]float64{"1": 0.09, "2": 0.06}, sys.CPU)
+	assert.Equal(t, map[string]float64{"1": 0.35, "2": 0.31}, sys.Memory)
+}
+
+func TestGetDDMMetrics_SystemFallback(t *testing.T) {
+	t.Parallel()
+
+	// without the private MIB, the model comes from sysDescr and there's no
+	// system monitor health
+	vars := append(testDDMVars(),
+		gosnmp.SnmpPDU{Name: "." + oidSysDescr, Type: gosnmp.OctetString, Value: []byte("TL-SG3428X")},
+	)
+
+	agent := newTestAgent(t, "public", vars)
+
+	client := NewSNMPClient("127.0.0.1", "public")
+	client.port = agent.port
+
+	result, err := client.GetDDMMetrics(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "TL-SG3428X", result.System.Model)
+	assert.Empty(t, result.System.Firmware)
+	assert.Empty(t, result.System.CPU)
+	assert.Empty(t, result.System.Memory)
+}
@@ -0,0 +1,106 @@
+package tplinkddm
+
+import "math"
+
+// Threshold states, used as the state label of tplink_sfp_threshold_state
+const (
+	ThresholdOK          = "ok"
+	ThresholdLowWarning  = "low_warning"
+	ThresholdHighWarning = "high_warning"
+	ThresholdLowAlarm    = "low_alarm"
+	ThresholdHighAlarm   = "high_alarm"
+)
+
+//nolint:gochecknoglobals // fixed label values
+var thresholdStates = []string{
+	ThresholdOK, ThresholdLowWarning, ThresholdHighWarning, ThresholdLowAlarm, ThresholdHighAlarm,
+}
+
+// thresholdMeasurements are the measurements with module thresholds, in the
+// order they're exported
+//
+//nolint:gochecknoglobals // lookup table
+var thresholdMeasurements = []string{FieldTemperature, FieldVoltage, FieldBiasCurrent, FieldTxPower, FieldRxPower}
+
+// reading returns a measurement's current value and its high alarm, low
+// alarm, high warning and low warning thresholds
+func (m *DDMMetrics) reading(field string) (v float64, thresholds [4]float64) {
+	switch field {
+	case FieldTemperature:
+		return m.Temperature, [4]float64{
+			m.TemperatureHighAlarm, m.TemperatureLowAlarm, m.TemperatureHighWarning, m.TemperatureLowWarning,
+		}
+	case FieldVoltage:
+		return m.Voltage, [4]float64{m.VoltageHighAlarm, m.VoltageLowAlarm, m.VoltageHighWarning, m.VoltageLowWarning}
+	case FieldBiasCurrent:
+		return m.BiasCurrent, [4]float64{
+			m.BiasCurrentHighAlarm, m.BiasCurrentLowAlarm, m.BiasCurrentHighWarning, m.BiasCurrentLowWarning,
+		}
+	case FieldTxPower:
+		return m.TxPower, [4]float64{m.TxPowerHighAlarm, m.TxPowerLowAlarm, m.TxPowerHighWarning, m.TxPowerLowWarning}
+	case FieldRxPower:
+		return m.RxPower, [4]float64{m.RxPowerHighAlarm, m.RxPowerLowAlarm, m.RxPowerHighWarning, m.RxPowerLowWarning}
+	}
+
+	return 0, [4]float64{}
+}
+
+// ThresholdState compares a measurement's reading against the module's
+// thresholds. It returns the most severe threshold crossed (or ThresholdOK),
+// and the signed distance to the nearest threshold, crossed or not, in the
+// measurement's unit: positive while short of it, negative once past it. A
+// reading past a warning but nearer the alarm gets the positive distance to
+// the alarm. ok is false if the
+// reading is missing, or the module has no usable thresholds (none reported,
+// or all zero).
+func (m *DDMMetrics) ThresholdState(field string) (state string, margin float64, ok bool) {
+	if !m.Valid(field) {
+		return "", 0, false
+	}
+
+	v, values := m.reading(field)
+
+	// alarms come first, so an alarm wins over a warning
+	thresholds := []struct {
+		state, level, typ string
+		high              bool
+	}{
+		{ThresholdHighAlarm, "high", "alarm", true},
+		{ThresholdLowAlarm, "low", "alarm", false},
+		{ThresholdHighWarning, "high", "warning", true},
+		{ThresholdLowWarning, "low", "warning", false},
+	}
+
+	state = ThresholdOK
+	margin = math.Inf(1)
+	allZero := true
+
+	for i, t := range thresholds {
+		if !m.Valid(thresholdField(field, t.level, t.typ)) {
+			continue
+		}
+
+		ok = true
+		allZero = allZero && values[i] == 0
+
+		d := v - values[i]
+		if t.high {
+			d = values[i] - v
+		}
+
+		// on a tie, the crossed threshold is the nearer one
+		if math.Abs(d) < math.Abs(margin) || (math.Abs(d) == math.Abs(margin) && d < margin) {
+			margin = d
+		}
+
+		if d < 0 && state == ThresholdOK {
+			state = t.state
+		}
+	}
+
+	if !ok || allZero {
+		return "", 0, false
+	}
+
+	return state, margin, true
+}
@@ -0,0 +1,64 @@
+package tplinkddm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdState(t *testing.T) {
+	base := DDMMetrics{RxPowerHighAlarm: 2, RxPowerLowAlarm: -20, RxPowerHighWarning: 1, RxPowerLowWarning: -18}
+
+	tests := []struct {
+		name       string
+		state      string
+		rx, margin float64
+	}{
+		{"ok", ThresholdOK, -5, 6},
+		{"low warning", ThresholdLowWarning, -19, -1},
+		{"low alarm", ThresholdLowAlarm, -21, -1},
+		{"high warning", ThresholdHighWarning, 1.5, -0.5},
+		{"past warning, nearer it", ThresholdHighWarning, 1.2, -0.2},
+		{"past warning, nearer alarm", ThresholdHighWarning, 1.8, 0.2},
+		{"high alarm", ThresholdHighAlarm, 3, -1},
+		{"on the threshold", ThresholdOK, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := base
+			m.RxPower = tt.rx
+
+			state, margin, ok := m.ThresholdState(FieldRxPower)
+			assert.True(t, ok)
+			assert.Equal(t, tt.state, state)
+			assert.InDelta(t, tt.margin, margin, 0.001)
+		})
+	}
+
+	t.Run("missing reading", func(t *testing.T) {
+		m := base
+		m.setInvalid(FieldRxPower)
+
+		_, _, ok := m.ThresholdState(FieldRxPower)
+		assert.False(t, ok)
+	})
+
+	t.Run("no thresholds", func(t *testing.T) {
+		m := DDMMetrics{RxPower: -5}
+
+		_, _, ok := m.ThresholdState(FieldRxPower)
+		assert.False(t, ok)
+	})
+
+	t.Run("missing thresholds are ignored", func(t *testing.T) {
+		m := base
+		m.RxPower = -19
+		m.setInvalid(thresholdField(FieldRxPower, "low", "warning"))
+
+		state, margin, ok := m.ThresholdState(FieldRxPower)
+		assert.True(t, ok)
+		assert.Equal(t, ThresholdOK, state)
+		assert.InDelta(t, 1, margin, 0.001)
+	})
+}
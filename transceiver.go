@@ -0,0 +1,130 @@
+package tplinkddm
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ENTITY-MIB entPhysicalTable columns, walked as a fallback when the switch
+// doesn't fill in the transceiver information table at all
+const (
+	oidEntPhysicalHardwareRev = "1.3.6.1.2.1.47.1.1.1.1.8"
+	oidEntPhysicalName        = "1.3.6.1.2.1.47.1.1.1.1.7"
+	oidEntPhysicalSerialNum   = "1.3.6.1.2.1.47.1.1.1.1.11"
+	oidEntPhysicalMfgName     = "1.3.6.1.2.1.47.1.1.1.1.12"
+	oidEntPhysicalModelName   = "1.3.6.1.2.1.47.1.1.1.1.13"
+)
+
+// entityRow holds the raw ENTITY-MIB values for one entPhysicalIndex
+type entityRow struct {
+	name       string
+	vendor     string
+	partNumber string
+	serial     string
+	revision   string
+}
+
+// entityColumns maps each walked ENTITY-MIB column to its field in entityRow
+//
+//nolint:gochecknoglobals // lookup table
+var entityColumns = []tableColumn[entityRow]{
+	{oid: oidEntPhysicalName, field: func(r *entityRow) *string { return &r.name }},
+	{oid: oidEntPhysicalMfgName, field: func(r *entityRow) *string { return &r.vendor }},
+	{oid: oidEntPhysicalModelName, field: func(r *entityRow) *string { return &r.partNumber }},
+	{oid: oidEntPhysicalSerialNum, field: func(r *entityRow) *string { return &r.serial }},
+	{oid: oidEntPhysicalHardwareRev, field: func(r *entityRow) *string { return &r.revision }},
+}
+
+// hasTransceiverInfo reports whether the switch returned any identifying
+// transceiver information for the row
+func (r *ddmRow) hasTransceiverInfo() bool {
+	return cleanInventoryString(r.vendor) != "" ||
+		cleanInventoryString(r.partNumber) != "" ||
+		cleanInventoryString(r.serial) != ""
+}
+
+// needsEntityFallback reports whether the private transceiver information
+// table is empty for every port, so ENTITY-MIB is worth walking. A switch
+// which fills it in leaves only empty cages out, and those aren't in
+// ENTITY-MIB either.
+func (d *ddmWalkData) needsEntityFallback() bool {
+	for _, r := range d.rows {
+		if r.port != "" && r.hasTransceiverInfo() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkEntityMIB walks the ENTITY-MIB columns into data.entityRows
+func (c *SNMPClient) walkEntityMIB(ctx context.Context, client *gosnmp.GoSNMP, data *ddmWalkData) {
+	ctx, span := tracer.Start(ctx, "SNMPClient.walkEntityMIB")
+	defer span.End()
+
+	client.Context = ctx
+
+	rows, err := walkColumns(client, entityColumns, &data.pduCount)
+	if err != nil {
+		slog.DebugContext(ctx, "ENTITY-MIB walk failed", "target", c.target, "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ENTITY-MIB walk failed")
+	}
+
+	data.entityRows = rows
+
+	span.SetAttributes(attribute.Int("snmp.entity_rows", len(data.entityRows)))
+}
+
+// matchEntity returns the physical entity describing the transceiver in the
+// given port: the first entity whose entPhysicalName ends in the port's
+// "unit/slot/port" name and which has a vendor, model or serial number. The
+// port's own (empty) entity is skipped this way.
+func matchEntity(entityRows map[string]*entityRow, iface string) (*entityRow, bool) {
+	for _, idx := range sortedKeys(entityRows) {
+		e := entityRows[idx]
+		if ifNameSuffix(e.name) != iface {
+			continue
+		}
+
+		if cleanInventoryString(e.vendor) != "" ||
+			cleanInventoryString(e.partNumber) != "" ||
+			cleanInventoryString(e.serial) != "" {
+			return e, true
+		}
+	}
+
+	return nil, false
+}
+
+// applyEntityRow copies the ENTITY-MIB inventory values onto m
+func applyEntityRow(m *DDMMetrics, e *entityRow) {
+	m.Vendor = cleanInventoryString(e.vendor)
+	m.PartNumber = cleanInventoryString(e.partNumber)
+	m.Serial = cleanInventoryString(e.serial)
+	m.Revision = cleanInventoryString(e.revision)
+}
+
+// cleanInventoryString trims the space and NUL padding SFP EEPROM fields
+// carry, and maps the placeholders some firmware reports to empty
+func cleanInventoryString(s string) string {
+	s = strings.Trim(s, " \t\r\n\x00")
+
+	switch s {
+	case "N/A", "---":
+		return ""
+	}
+
+	return s
+}
+
+// parseWavelength parses a wavelength in nm, accepting an optional "nm"
+// suffix (e.g. "1310" or "1310nm")
+func parseWavelength(s string) (float64, error) {
+	return parseFloat(strings.TrimSuffix(strings.ToLower(cleanInventoryString(s)), "nm"))
+}
@@ -0,0 +1,138 @@
+package tplinkddm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanInventoryString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "TP-LINK", cleanInventoryString("TP-LINK         "))
+	assert.Equal(t, "ABC123", cleanInventoryString("ABC123\x00\x00"))
+	assert.Empty(t, cleanInventoryString("N/A"))
+	assert.Empty(t, cleanInventoryString(" --- "))
+	assert.Empty(t, cleanInventoryString(""))
+}
+
+func TestParseWavelength(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]float64{"1310": 1310, "1550nm": 1550, " 850 nm ": 850, "1270.5": 1270.5} {
+		got, err := parseWavelength(in)
+		require.NoError(t, err, in)
+		assert.InDelta(t, want, got, 0, in)
+	}
+
+	for _, in := range []string{"", "N/A", "nm", "copper"} {
+		_, err := parseWavelength(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestMatchEntity(t *testing.T) {
+	t.Parallel()
+
+	entities := map[string]*entityRow{
+		"1":   {name: "T2600G-28SQ"},
+		"125": {name: "gigabitEthernet 1/0/25"},
+		"225": {name: "SFP 1/0/25", vendor: "FS", partNumber: "SFP-10GLR-31", serial: "F123"},
+		"226": {name: "SFP 1/0/26", vendor: "  ", serial: "\x00"},
+	}
+
+	e, ok := matchEntity(entities, "1/0/25")
+	require.True(t, ok)
+	assert.Equal(t, "FS", e.vendor)
+
+	_, ok = matchEntity(entities, "1/0/26")
+	assert.False(t, ok)
+
+	_, ok = matchEntity(nil, "1/0/25")
+	assert.False(t, ok)
+}
+
+func TestGetDDMMetrics_Transceiver(t *testing.T) {
+	t.Parallel()
+
+	str := func(oid, idx, val string) gosnmp.SnmpPDU {
+		return gosnmp.SnmpPDU{Name: "." + oid + "." + idx, Type: gosnmp.OctetString, Value: []byte(val)}
+	}
+
+	entity := []gosnmp.SnmpPDU{
+		str(oidEntPhysicalName, "101", "SFP 1/0/1"),
+		str(oidEntPhysicalMfgName, "101", "OEM"),
+		str(oidEntPhysicalSerialNum, "101", "E0000001"),
+		str(oidEntPhysicalName, "102", "gigabitEthernet 1/0/2"),
+		str(oidEntPhysicalName, "202", "SFP 1/0/2"),
+		str(oidEntPhysicalMfgName, "202", "FS"),
+		str(oidEntPhysicalModelName, "202", "SFP-10GSR-85"),
+		str(oidEntPhysicalSerialNum, "202", "F2010203"),
+		str(oidEntPhysicalHardwareRev, "202", "A"),
+	}
+
+	t.Run("private table", func(t *testing.T) {
+		t.Parallel()
+
+		vars := append(testDDMVars(),
+			// port 1 is in the private transceiver table, port 2 isn't, as
+			// for an empty cage
+			str(oidSFPInfoVendor, "49153", "TP-LINK         "),
+			str(oidSFPInfoPartNumber, "49153", "TL-SM5110-LR    "),
+			str(oidSFPInfoSerial, "49153", "2190418001234"),
+			str(oidSFPInfoRevision, "49153", "1.0"),
+			str(oidSFPInfoWavelength, "49153", "1310nm"),
+			str(oidSFPInfoConnector, "49153", "LC"),
+			str(oidSFPInfoMediaType, "49153", "10GBASE-LR"),
+		)
+		vars = append(vars, entity...)
+
+		agent := newTestAgent(t, "public", vars)
+
+		client := NewSNMPClient("127.0.0.1", "public")
+		client.port = agent.port
+
+		result, err := client.GetDDMMetrics(context.Background())
+		require.NoError(t, err)
+		require.Len(t, result.Metrics, 2)
+
+		p1 := result.Metrics[0]
+		assert.Equal(t, "TP-LINK", p1.Vendor)
+		assert.Equal(t, "TL-SM5110-LR", p1.PartNumber)
+		assert.Equal(t, "2190418001234", p1.Serial)
+		assert.Equal(t, "1.0", p1.Revision)
+		assert.InDelta(t, 1310, p1.Wavelength, 0)
+		assert.Equal(t, "LC", p1.Connector)
+		assert.Equal(t, "10GBASE-LR", p1.MediaType)
+
+		// ENTITY-MIB isn't walked just because one port has no inventory
+		assert.Empty(t, result.Metrics[1].Vendor)
+		assert.Empty(t, result.Metrics[1].Serial)
+	})
+
+	t.Run("ENTITY-MIB fallback", func(t *testing.T) {
+		t.Parallel()
+
+		agent := newTestAgent(t, "public", append(testDDMVars(), entity...))
+
+		client := NewSNMPClient("127.0.0.1", "public")
+		client.port = agent.port
+
+		result, err := client.GetDDMMetrics(context.Background())
+		require.NoError(t, err)
+		require.Len(t, result.Metrics, 2)
+
+		assert.Equal(t, "OEM", result.Metrics[0].Vendor)
+		assert.Equal(t, "E0000001", result.Metrics[0].Serial)
+
+		p2 := result.Metrics[1]
+		assert.Equal(t, "FS", p2.Vendor)
+		assert.Equal(t, "SFP-10GSR-85", p2.PartNumber)
+		assert.Equal(t, "F2010203", p2.Serial)
+		assert.Equal(t, "A", p2.Revision)
+		assert.Zero(t, p2.Wavelength)
+	})
+}